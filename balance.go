@@ -0,0 +1,171 @@
+package bankserv
+
+import (
+	"github.com/dottics/dutil"
+	"sort"
+	"time"
+)
+
+// Net returns the net amount of the transaction, which is the sum of the
+// amount minus the discount of each of the items of the transaction. The
+// amounts are signed, a credit to the bank account is positive and a debit
+// is negative. A transaction without items has a zero net amount.
+//
+// An error with the key "currency" is returned if the items are not all in
+// the same currency.
+func (t Transaction) Net() (Money, dutil.Error) {
+	net := Money{}
+	for j, i := range t.Items {
+		a := i.Amount
+		// an item without a discount has a zero discount in any currency
+		if !i.Discount.IsZero() {
+			var e dutil.Error
+			a, e = i.Amount.Sub(i.Discount)
+			if e != nil {
+				return Money{}, e
+			}
+		}
+		if j == 0 {
+			net = a
+			continue
+		}
+		var e dutil.Error
+		net, e = net.Add(a)
+		if e != nil {
+			return Money{}, e
+		}
+	}
+	return net, nil
+}
+
+// BalancePoint is the balance of a bank account after a transaction.
+type BalancePoint struct {
+	Transaction Transaction
+	// Net is the net amount of the transaction
+	Net Money
+	// Balance is the balance after the transaction
+	Balance Money
+}
+
+// RunningBalance returns the balance of a bank account after each of the
+// transactions, starting from the opening balance. The transactions are
+// sorted by date, transactions on the same date keep their order, the
+// transactions passed to the function are not modified.
+//
+// An error with the key "currency" is returned if a transaction is not in the
+// currency of the opening balance.
+func RunningBalance(opening Money, xt Transactions) ([]BalancePoint, dutil.Error) {
+	sorted := make(Transactions, len(xt))
+	copy(sorted, xt)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	balance := opening
+	xp := make([]BalancePoint, 0, len(sorted))
+	for _, t := range sorted {
+		net, e := t.Net()
+		if e != nil {
+			return nil, e
+		}
+		balance, e = addNet(balance, net)
+		if e != nil {
+			return nil, e
+		}
+		xp = append(xp, BalancePoint{
+			Transaction: t,
+			Net:         net,
+			Balance:     balance,
+		})
+	}
+	return xp, nil
+}
+
+// BalanceAsOf returns the balance at the end of the date passed to the
+// function, which is the opening balance plus the net amount of every
+// transaction up to and including the date. Dates are compared in the
+// location of the date passed to the function.
+func BalanceAsOf(opening Money, xt Transactions, date time.Time) (Money, dutil.Error) {
+	y, m, d := date.Date()
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, date.Location())
+	balance := opening
+	for _, t := range xt {
+		if !t.Date.Before(end) {
+			continue
+		}
+		net, e := t.Net()
+		if e != nil {
+			return Money{}, e
+		}
+		balance, e = addNet(balance, net)
+		if e != nil {
+			return Money{}, e
+		}
+	}
+	return balance, nil
+}
+
+// BalanceAsOf returns the balance of the bank account at the end of the date
+// passed to the function from the opening balance of the bank account and
+// the transactions of the bank account. Transactions of other bank accounts
+// are ignored.
+//
+// The bank-service does not send the currency of an item, therefore, the
+// amounts and discounts of the items without a currency are taken to be in
+// the Currency of the bank account. An amount without a currency is decoded
+// with two decimals, which makes the balance of a currency with three minor
+// unit digits exact to two decimals only.
+func (b BankAccount) BalanceAsOf(xt Transactions, date time.Time) (Money, dutil.Error) {
+	currency := NewMoney(0, b.Currency).currency()
+	own := make(Transactions, 0, len(xt))
+	for _, t := range xt {
+		if t.AccountUUID != b.UUID {
+			continue
+		}
+		t, e := t.inCurrency(currency)
+		if e != nil {
+			return Money{}, e
+		}
+		own = append(own, t)
+	}
+	opening := b.OpeningBalance
+	if opening.Currency == "" {
+		opening.Currency = currency
+	}
+	return BalanceAsOf(opening, own, date)
+}
+
+// inCurrency returns a copy of the transaction of which the amounts and
+// discounts of the items without a currency are in the currency passed to
+// the function. The amounts are converted to the minor unit digits of the
+// currency, for example 15.00 in the default currency is 15 yen.
+func (t Transaction) inCurrency(currency string) (Transaction, dutil.Error) {
+	convert := func(m Money) (Money, dutil.Error) {
+		if m.Currency != "" {
+			return m, nil
+		}
+		return ParseMoney(m.Decimal(), currency)
+	}
+	items := make(Items, len(t.Items))
+	for j, i := range t.Items {
+		var e dutil.Error
+		if i.Amount, e = convert(i.Amount); e != nil {
+			return Transaction{}, e
+		}
+		if i.Discount, e = convert(i.Discount); e != nil {
+			return Transaction{}, e
+		}
+		items[j] = i
+	}
+	t.Items = items
+	return t, nil
+}
+
+// addNet adds the net amount of a transaction to the balance. The zero net
+// amount of a transaction without items is in any currency.
+func addNet(balance, net Money) (Money, dutil.Error) {
+	if net.IsZero() {
+		return balance, nil
+	}
+	return balance.Add(net)
+}
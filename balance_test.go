@@ -0,0 +1,250 @@
+package bankserv
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// txn returns a transaction of the bank account on the date with an item for
+// each of the amounts in cents.
+func txn(date string, amounts ...int64) Transaction {
+	t := Transaction{
+		AccountUUID: uuid.MustParse("032203af-6002-4abc-9982-73c577add8df"),
+		Date:        timeMustParse(date),
+		Description: date,
+	}
+	for _, a := range amounts {
+		t.Items = append(t.Items, Item{Amount: Money{MinorUnits: a}})
+	}
+	return t
+}
+
+func TestTransaction_Net(t *testing.T) {
+	tt := []struct {
+		name        string
+		transaction Transaction
+		net         Money
+		e           dutil.Error
+	}{
+		{
+			name:        "no items",
+			transaction: Transaction{},
+			net:         Money{},
+			e:           nil,
+		},
+		{
+			name:        "amount minus discount",
+			transaction: Transaction{Items: Items{milk}},
+			net:         Money{MinorUnits: 2249},
+			e:           nil,
+		},
+		{
+			name: "sum of items",
+			transaction: Transaction{Items: Items{
+				{Amount: Money{MinorUnits: -2499}, Discount: Money{MinorUnits: -250}},
+				{Amount: Money{MinorUnits: -1}},
+				{Amount: Money{MinorUnits: -10}, Discount: Money{MinorUnits: -10}},
+			}},
+			net: Money{MinorUnits: -2250},
+			e:   nil,
+		},
+		{
+			name: "currency of the items",
+			transaction: Transaction{Items: Items{
+				{Amount: NewMoney(1000, "USD")},
+				{Amount: NewMoney(-250, "USD"), Discount: NewMoney(-50, "USD")},
+			}},
+			net: NewMoney(800, "USD"),
+			e:   nil,
+		},
+		{
+			name: "mixed currencies",
+			transaction: Transaction{Items: Items{
+				{Amount: NewMoney(1000, "USD")},
+				{Amount: NewMoney(1000, "ZAR")},
+			}},
+			net: Money{},
+			e:   dutil.NewErr(400, "currency", []string{"currency mismatch USD and ZAR"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			net, e := tc.transaction.Net()
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if net != tc.net {
+				t.Errorf("expected net %v got %v", tc.net, net)
+			}
+		})
+	}
+}
+
+func TestRunningBalance(t *testing.T) {
+	xt := Transactions{
+		txn("2022-06-03T08:00:00Z", -2499, -1),
+		txn("2022-06-01T08:00:00Z", 1000000),
+		txn("2022-06-03T08:00:00Z", -10),
+		txn("2022-06-02T08:00:00Z"),
+	}
+	xp, e := RunningBalance(Money{MinorUnits: 10}, xt)
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+
+	dates := []string{"2022-06-01", "2022-06-02", "2022-06-03", "2022-06-03"}
+	nets := []int64{1000000, 0, -2500, -10}
+	balances := []int64{1000010, 1000010, 997510, 997500}
+	if len(xp) != len(dates) {
+		t.Fatalf("expected %d points got %d", len(dates), len(xp))
+	}
+	for i, p := range xp {
+		if p.Transaction.Date.Format("2006-01-02") != dates[i] {
+			t.Errorf("expected point %d on %s got %s", i, dates[i], p.Transaction.Date.Format("2006-01-02"))
+		}
+		if p.Net.MinorUnits != nets[i] {
+			t.Errorf("expected point %d net %d got %d", i, nets[i], p.Net.MinorUnits)
+		}
+		if p.Balance.MinorUnits != balances[i] {
+			t.Errorf("expected point %d balance %d got %d", i, balances[i], p.Balance.MinorUnits)
+		}
+	}
+	// transactions on the same date keep their order
+	if len(xp[2].Transaction.Items) != 2 {
+		t.Errorf("expected the order of transactions on the same date to be kept")
+	}
+	// the transactions passed to the function are not sorted
+	if xt[0].Date.Day() != 3 {
+		t.Errorf("expected the transactions not to be modified")
+	}
+
+	t.Run("currency mismatch", func(t *testing.T) {
+		_, e := RunningBalance(NewMoney(0, "USD"), xt)
+		if e == nil {
+			t.Errorf("expected a currency error")
+		}
+	})
+}
+
+func TestBalanceAsOf(t *testing.T) {
+	xt := Transactions{
+		txn("2022-06-01T08:00:00Z", 1000000),
+		txn("2022-06-03T23:59:59Z", -2500),
+		txn("2022-06-04T00:00:00Z", -10),
+	}
+	sast := time.FixedZone("SAST", 2*60*60)
+
+	tt := []struct {
+		name    string
+		date    time.Time
+		balance int64
+	}{
+		{
+			name:    "before the first transaction",
+			date:    timeMustParse("2022-05-31T12:00:00Z"),
+			balance: 500,
+		},
+		{
+			name:    "end of the date",
+			date:    timeMustParse("2022-06-03T00:00:00Z"),
+			balance: 997500 + 500,
+		},
+		{
+			name:    "every transaction",
+			date:    timeMustParse("2022-06-04T00:00:00Z"),
+			balance: 997490 + 500,
+		},
+		{
+			name:    "location of the date",
+			date:    time.Date(2022, 6, 3, 12, 0, 0, 0, sast),
+			balance: 1000000 + 500,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			balance, e := BalanceAsOf(Money{MinorUnits: 500}, xt, tc.date)
+			if e != nil {
+				t.Errorf("unexpected error %v", e)
+			}
+			if balance.MinorUnits != tc.balance {
+				t.Errorf("expected balance %d got %d", tc.balance, balance.MinorUnits)
+			}
+		})
+	}
+
+	t.Run("bank account", func(t *testing.T) {
+		ba := BankAccount{
+			UUID:           uuid.MustParse("032203af-6002-4abc-9982-73c577add8df"),
+			OpeningBalance: Money{MinorUnits: 500},
+		}
+		other := txn("2022-06-02T08:00:00Z", 123)
+		other.AccountUUID = uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb")
+		balance, e := ba.BalanceAsOf(append(Transactions{other}, xt...), timeMustParse("2022-06-30T00:00:00Z"))
+		if e != nil {
+			t.Errorf("unexpected error %v", e)
+		}
+		if balance.MinorUnits != 997990 {
+			t.Errorf("expected balance %d got %d", 997990, balance.MinorUnits)
+		}
+	})
+}
+
+func TestBankAccount_BalanceAsOf_currency(t *testing.T) {
+	const transactionsJSON = `[
+		{"uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","date":"2022-06-18T15:26:22Z","description":"GOOGLE STORAGE","items":[{"description":"storage","sku":1,"amount":-1.99,"discount":0,"active":true}],"active":true},
+		{"uuid":"d25ac3b1-0a8f-43a3-8da1-d2f22a814a82","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","date":"2022-06-20T08:00:00Z","description":"REFUND","items":[{"description":"one","sku":1,"amount":25,"discount":0.5,"active":true},{"description":"two","sku":1,"amount":"10.25","active":true}],"active":true}
+	]`
+
+	tt := []struct {
+		name        string
+		bankAccount string
+		balance     Money
+		decimal     string
+	}{
+		{
+			name:        "usd",
+			bankAccount: `{"uuid":"032203af-6002-4abc-9982-73c577add8df","currency":"USD","opening_balance":100.00}`,
+			balance:     NewMoney(13276, "USD"),
+			decimal:     "132.76",
+		},
+		{
+			name:        "currency without minor units",
+			bankAccount: `{"uuid":"032203af-6002-4abc-9982-73c577add8df","currency":"jpy","opening_balance":1000}`,
+			// -1.99 is -2 yen, 25 - 0.5 is 25 - 1 yen and 10.25 is 10 yen
+			balance: NewMoney(1032, "JPY"),
+			decimal: "1032",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ba := BankAccount{}
+			if err := json.Unmarshal([]byte(tc.bankAccount), &ba); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			xt := Transactions{}
+			if err := json.Unmarshal([]byte(transactionsJSON), &xt); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			balance, e := ba.BalanceAsOf(xt, timeMustParse("2022-06-30T00:00:00Z"))
+			if e != nil {
+				t.Fatalf("unexpected error %v", e)
+			}
+			if !balance.Equal(tc.balance) || balance.Decimal() != tc.decimal {
+				t.Errorf("expected balance %v got %v", tc.balance, balance)
+			}
+			if xt[0].Items[0].Amount.Currency != "" {
+				t.Errorf("expected the transactions passed to the function to be unchanged got %v", xt[0].Items[0].Amount)
+			}
+		})
+	}
+}
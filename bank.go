@@ -1,41 +1,232 @@
 package bankserv
 
-import "github.com/dottics/dutil"
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+)
 
 // GetBanks gets all the banks from the bank-service.
 func (s *Service) GetBanks() (Banks, dutil.Error) {
-	// get access to the msp service
-	ms := s.serv
-	// create and make request
-	ms.URL.Path = "/bank"
-	res, e := ms.NewRequest("GET", ms.URL.String(), nil, nil)
+	return s.GetBanksContext(context.Background())
+}
+
+// GetBanksContext is the same as GetBanks, the context passed to the function
+// is used to cancel the exchange with the bank-service.
+func (s *Service) GetBanksContext(ctx context.Context) (Banks, dutil.Error) {
+	xb := Banks{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/bank",
+		status: 200,
+		key:    "banks",
+		data:   &xb,
+	})
 	if e != nil {
 		return Banks{}, e
 	}
+	return xb, nil
+}
 
-	// response structure
-	resp := struct {
-		Message string `json:"message"`
-		Data    struct {
-			Banks Banks `json:"banks"`
-		} `json:"data"`
-		Errors dutil.Errors `json:"errors"`
-	}{}
+// GetBank gets a specific bank from the bank-service based on the bank's UUID
+// passed to the function. If the bank does not exist an error with the status
+// 404 is returned.
+func (s *Service) GetBank(UUID uuid.UUID) (Bank, dutil.Error) {
+	return s.GetBankContext(context.Background(), UUID)
+}
 
-	// decode the response
-	_, e = ms.Decode(res, &resp)
+// GetBankContext is the same as GetBank, the context passed to the function
+// is used to cancel the exchange with the bank-service.
+func (s *Service) GetBankContext(ctx context.Context, UUID uuid.UUID) (Bank, dutil.Error) {
+	b := Bank{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/bank/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "bank",
+		data:   &b,
+	})
 	if e != nil {
-		return Banks{}, e
+		return Bank{}, e
+	}
+	if b.UUID == uuid.Nil {
+		return Bank{}, notFound("bank")
 	}
+	return b, nil
+}
 
-	// check response for error
-	if res.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: res.StatusCode,
-			Errors: resp.Errors,
-		}
-		return Banks{}, e
+// GetBankByBranchCode gets a specific bank from the bank-service based on the
+// bank's branch code passed to the function. If the bank does not exist an
+// error with the status 404 is returned.
+func (s *Service) GetBankByBranchCode(branchCode string) (Bank, dutil.Error) {
+	return s.GetBankByBranchCodeContext(context.Background(), branchCode)
+}
+
+// GetBankByBranchCodeContext is the same as GetBankByBranchCode, the context
+// passed to the function is used to cancel the exchange with the bank-service.
+func (s *Service) GetBankByBranchCodeContext(ctx context.Context, branchCode string) (Bank, dutil.Error) {
+	b := Bank{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/bank/-",
+		query:  url.Values{"branch_code": {normaliseBranchCode(branchCode)}},
+		status: 200,
+		key:    "bank",
+		data:   &b,
+	})
+	if e != nil {
+		return Bank{}, e
+	}
+	if b.UUID == uuid.Nil {
+		return Bank{}, notFound("bank")
 	}
+	return b, nil
+}
 
-	return resp.Data.Banks, nil
+// CreateBank creates a new bank based on the bank data passed to the function
+// and returns the bank that has been created.
+func (s *Service) CreateBank(b Bank) (Bank, dutil.Error) {
+	return s.CreateBankContext(context.Background(), b)
+}
+
+// CreateBankContext is the same as CreateBank, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) CreateBankContext(ctx context.Context, b Bank) (Bank, dutil.Error) {
+	if e := b.Validate(); e != nil {
+		return Bank{}, e
+	}
+	bank := Bank{}
+	e := s.do(ctx, exchange{
+		method:  "POST",
+		path:    "/bank",
+		payload: b,
+		status:  201,
+		key:     "bank",
+		data:    &bank,
+	})
+	if e != nil {
+		return Bank{}, e
+	}
+	return bank, nil
+}
+
+// UpdateBank updates a specific bank's data based on the bank's UUID and the
+// bank data passed to the function.
+func (s *Service) UpdateBank(b Bank) (Bank, dutil.Error) {
+	return s.UpdateBankContext(context.Background(), b)
+}
+
+// UpdateBankContext is the same as UpdateBank, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) UpdateBankContext(ctx context.Context, b Bank) (Bank, dutil.Error) {
+	if e := b.Validate(); e != nil {
+		return Bank{}, e
+	}
+	bank := Bank{}
+	e := s.do(ctx, exchange{
+		method:  "PUT",
+		path:    "/bank/-",
+		payload: b,
+		status:  200,
+		key:     "bank",
+		data:    &bank,
+	})
+	if e != nil {
+		return Bank{}, e
+	}
+	return bank, nil
+}
+
+// DeleteBank deletes a specific bank based on the bank's UUID. It only returns
+// an error if an error has occurred.
+func (s *Service) DeleteBank(UUID uuid.UUID) dutil.Error {
+	return s.DeleteBankContext(context.Background(), UUID)
+}
+
+// DeleteBankContext is the same as DeleteBank, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) DeleteBankContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	return s.do(ctx, exchange{
+		method: "DELETE",
+		path:   "/bank/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+	})
+}
+
+// BankIndex is an in-memory index of banks to look up a bank without an
+// exchange with the bank-service, for example to resolve the branch code of
+// every row of a bank statement.
+type BankIndex struct {
+	byUUID       map[uuid.UUID]Bank
+	byBranchCode map[string]Bank
+}
+
+// NewBankIndex creates a BankIndex from the banks passed to the function,
+// usually the banks from GetBanks.
+func NewBankIndex(xb Banks) BankIndex {
+	bi := BankIndex{
+		byUUID:       make(map[uuid.UUID]Bank, len(xb)),
+		byBranchCode: make(map[string]Bank, len(xb)),
+	}
+	for _, b := range xb {
+		bi.byUUID[b.UUID] = b
+		bi.byBranchCode[normaliseBranchCode(b.BranchCode)] = b
+	}
+	return bi
+}
+
+// GetBankIndex gets all the banks from the bank-service and returns a
+// BankIndex of the banks.
+func (s *Service) GetBankIndex() (BankIndex, dutil.Error) {
+	return s.GetBankIndexContext(context.Background())
+}
+
+// GetBankIndexContext is the same as GetBankIndex, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) GetBankIndexContext(ctx context.Context) (BankIndex, dutil.Error) {
+	xb, e := s.GetBanksContext(ctx)
+	if e != nil {
+		return BankIndex{}, e
+	}
+	return NewBankIndex(xb), nil
+}
+
+// Bank looks up a bank by the bank's UUID and reports whether the bank was
+// found.
+func (bi BankIndex) Bank(UUID uuid.UUID) (Bank, bool) {
+	b, ok := bi.byUUID[UUID]
+	return b, ok
+}
+
+// BranchCode looks up a bank by the bank's branch code and reports whether
+// the bank was found. Branch codes are compared without surrounding spaces and
+// with the leading zeros that spreadsheets often drop.
+func (bi BankIndex) BranchCode(branchCode string) (Bank, bool) {
+	b, ok := bi.byBranchCode[normaliseBranchCode(branchCode)]
+	return b, ok
+}
+
+// Len returns the number of banks in the index.
+func (bi BankIndex) Len() int {
+	return len(bi.byUUID)
+}
+
+// normaliseBranchCode trims the spaces from a branch code and pads a numeric
+// branch code with leading zeros to the six digits of a South African branch
+// code.
+func normaliseBranchCode(branchCode string) string {
+	code := strings.TrimSpace(branchCode)
+	if code == "" || len(code) >= 6 {
+		return code
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return code
+		}
+	}
+	return strings.Repeat("0", 6-len(code)) + code
 }
@@ -1,203 +1,274 @@
 package bankserv
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
+	"net/http"
 	"net/url"
+	"strings"
 )
 
+// GetBankAccount gets a specific bank account based on the bank account's UUID
+// passed to the function. If the bank account does not exist an error with
+// the status 404 is returned.
+func (s *Service) GetBankAccount(UUID uuid.UUID) (BankAccount, dutil.Error) {
+	return s.GetBankAccountContext(context.Background(), UUID)
+}
+
+// GetBankAccountContext is the same as GetBankAccount, the context passed to
+// the function is used to cancel the exchange with the bank-service.
+func (s *Service) GetBankAccountContext(ctx context.Context, UUID uuid.UUID) (BankAccount, dutil.Error) {
+	ba := BankAccount{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/bank-account/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "bank_account",
+		data:   &ba,
+	})
+	if e != nil {
+		return BankAccount{}, e
+	}
+	if ba.UUID == uuid.Nil {
+		return BankAccount{}, notFound("bank_account")
+	}
+	return ba, nil
+}
+
 // GetUserBankAccounts gets all the bank accounts for a specific user based on
 // the user's UUID passed to the function and returns a slice of BankAccount.
 // If an error occurs such as the user not found then an empty slice is returned
 // and an error.
 func (s *Service) GetUserBankAccounts(UUID uuid.UUID) (BankAccounts, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/bank-account/user/-"
-	// add query string
-	qs := url.Values{"uuid": {UUID.String()}}
-	s.serv.URL.RawQuery = qs.Encode()
-	// do request
-	r, e := s.serv.NewRequest("GET", s.serv.URL.String(), nil, nil)
-	if e != nil {
-		return BankAccounts{}, e
-	}
-
-	// response structure
-	type Data struct {
-		BankAccounts `json:"bank_accounts"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors map[string][]string `json:"errors"`
-	}{}
+	return s.GetUserBankAccountsContext(context.Background(), UUID)
+}
 
-	// decode the response
-	_, e = s.serv.Decode(r, &res)
+// GetUserBankAccountsContext is the same as GetUserBankAccounts, the context
+// passed to the function is used to cancel the exchange with the bank-service.
+func (s *Service) GetUserBankAccountsContext(ctx context.Context, UUID uuid.UUID) (BankAccounts, dutil.Error) {
+	xba := BankAccounts{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/bank-account/user/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "bank_accounts",
+		data:   &xba,
+	})
 	if e != nil {
 		return BankAccounts{}, e
 	}
-
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
-		return BankAccounts{}, e
-	}
 	// return bank accounts on successful
-	return res.Data.BankAccounts, nil
+	return xba, nil
 }
 
 // GetOrganisationBankAccounts gets all the bank accounts for a specific
 // organisation based on the organisation's UUID and returns a slice of
 // BankAccount. If error occurs an error is returned.
 func (s *Service) GetOrganisationBankAccounts(UUID uuid.UUID) (BankAccounts, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/bank-account/organisation/-"
-	// set query string
-	qs := url.Values{"uuid": {UUID.String()}}
-	s.serv.URL.RawQuery = qs.Encode()
-	// do request
-	r, e := s.serv.NewRequest("GET", s.serv.URL.String(), nil, nil)
-	if e != nil {
-		return BankAccounts{}, e
-	}
-
-	type Data struct {
-		BankAccounts `json:"bank_accounts"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors map[string][]string
-	}{}
+	return s.GetOrganisationBankAccountsContext(context.Background(), UUID)
+}
 
-	// decode the response
-	_, e = s.serv.Decode(r, &res)
+// GetOrganisationBankAccountsContext is the same as
+// GetOrganisationBankAccounts, the context passed to the function is used to
+// cancel the exchange with the bank-service.
+func (s *Service) GetOrganisationBankAccountsContext(ctx context.Context, UUID uuid.UUID) (BankAccounts, dutil.Error) {
+	xba := BankAccounts{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/bank-account/organisation/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "bank_accounts",
+		data:   &xba,
+	})
 	if e != nil {
 		return BankAccounts{}, e
 	}
-
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
-		return BankAccounts{}, e
-	}
 	// return the bank accounts on successful
-	return res.Data.BankAccounts, nil
+	return xba, nil
 }
 
 // CreateBankAccount creates a new bank account for either the user or
 // organisation based on which UUID is provided. After creating the bank account
 // it returns the bank account, or if an error occurs an error is returned.
+// Every create is sent with a new idempotency key, use
+// CreateBankAccountContext and WithIdempotencyKey to retry a create safely.
 func (s *Service) CreateBankAccount(b BankAccount) (BankAccount, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/bank-account"
-	// marshal data to payload reader
-	p, e := dutil.MarshalReader(b)
-	if e != nil {
-		return BankAccount{}, e
-	}
+	return s.CreateBankAccountContext(context.Background(), b)
+}
 
-	// do request
-	r, e := s.serv.NewRequest("POST", s.serv.URL.String(), nil, p)
-	if e != nil {
+// CreateBankAccountContext is the same as CreateBankAccount, the context passed
+// to the function is used to cancel the exchange with the bank-service.
+//
+// If the context carries an idempotency key, see WithIdempotencyKey, a create
+// with the key of a previous successful create returns the original bank
+// account instead of creating a second bank account.
+func (s *Service) CreateBankAccountContext(ctx context.Context, b BankAccount) (BankAccount, dutil.Error) {
+	if e := b.Validate(); e != nil {
 		return BankAccount{}, e
 	}
-
-	type Data struct {
-		BankAccount `json:"bank_account"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors map[string][]string
-	}{}
-	// decode the response
-	_, e = s.serv.Decode(r, &res)
+	v, e := s.idempotent(ctx, "bank_account", func(header http.Header) (interface{}, dutil.Error) {
+		ba := BankAccount{}
+		e := s.do(ctx, exchange{
+			method:  "POST",
+			path:    "/bank-account",
+			header:  header,
+			payload: b,
+			status:  201,
+			key:     "bank_account",
+			data:    &ba,
+		})
+		return ba, e
+	})
 	if e != nil {
 		return BankAccount{}, e
 	}
-
-	if r.StatusCode != 201 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
-		return BankAccount{}, e
-	}
 	// return bank account on successful
-	return res.Data.BankAccount, nil
+	return v.(BankAccount), nil
 }
 
 // UpdateBankAccount updates a specific bank account's data.
 func (s *Service) UpdateBankAccount(b BankAccount) (BankAccount, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/bank-account/-"
-	// marshal payload reader
-	p, e := dutil.MarshalReader(b)
-	if e != nil {
-		return BankAccount{}, e
-	}
-	// do request
-	r, e := s.serv.NewRequest("PUT", s.serv.URL.String(), nil, p)
-	if e != nil {
-		return BankAccount{}, e
-	}
+	return s.UpdateBankAccountContext(context.Background(), b)
+}
 
-	type Data struct {
-		BankAccount `json:"bank_account"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors map[string][]string `json:"errors"`
-	}{}
-	// decode response
-	_, e = s.serv.Decode(r, &res)
-	if e != nil {
+// UpdateBankAccountContext is the same as UpdateBankAccount, the context passed
+// to the function is used to cancel the exchange with the bank-service.
+func (s *Service) UpdateBankAccountContext(ctx context.Context, b BankAccount) (BankAccount, dutil.Error) {
+	if e := validateUpdate(b.UUID, b); e != nil {
 		return BankAccount{}, e
 	}
-
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
+	ba := BankAccount{}
+	e := s.do(ctx, exchange{
+		method:  "PUT",
+		path:    "/bank-account/-",
+		payload: b,
+		status:  200,
+		key:     "bank_account",
+		data:    &ba,
+	})
+	if e != nil {
 		return BankAccount{}, e
 	}
 	// return bank account on successful
-	return res.Data.BankAccount, nil
+	return ba, nil
 }
 
 // DeleteBankAccount deletes a specific bank account's data.
 func (s *Service) DeleteBankAccount(UUID uuid.UUID) dutil.Error {
-	// set path
-	s.serv.URL.Path = "/bank-account/-"
-	qs := url.Values{"uuid": {UUID.String()}}
-	s.serv.URL.RawQuery = qs.Encode()
+	return s.DeleteBankAccountContext(context.Background(), UUID)
+}
 
-	// do request
-	r, e := s.serv.NewRequest("DELETE", s.serv.URL.String(), nil, nil)
-	if e != nil {
-		return e
+// DeleteBankAccountContext is the same as DeleteBankAccount, the context passed
+// to the function is used to cancel the exchange with the bank-service.
+func (s *Service) DeleteBankAccountContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	return s.do(ctx, exchange{
+		method: "DELETE",
+		path:   "/bank-account/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+	})
+}
+
+// UnmarshalJSON unmarshals a bank account and its opening balance in the
+// currency of the bank account.
+func (b *BankAccount) UnmarshalJSON(xb []byte) error {
+	// bankAccount does not have the UnmarshalJSON method of BankAccount
+	type bankAccount BankAccount
+	x := struct {
+		*bankAccount
+		OpeningBalance json.RawMessage `json:"opening_balance"`
+	}{
+		bankAccount: (*bankAccount)(b),
+	}
+	err := json.Unmarshal(xb, &x)
+	if err != nil {
+		return err
+	}
+	b.OpeningBalance = NewMoney(0, b.Currency)
+	if len(x.OpeningBalance) > 0 {
+		return b.OpeningBalance.UnmarshalJSON(x.OpeningBalance)
 	}
+	return nil
+}
 
-	res := struct {
-		Errors map[string][]string `json:"errors"`
-	}{}
+// MarshalJSON marshals a bank account without the bank_uuid and
+// opening_balance if they are not set.
+func (b BankAccount) MarshalJSON() ([]byte, error) {
+	// bankAccount does not have the MarshalJSON method of BankAccount
+	type bankAccount BankAccount
+	x := struct {
+		bankAccount
+		BankUUID       *uuid.UUID `json:"bank_uuid,omitempty"`
+		OpeningBalance *Money     `json:"opening_balance,omitempty"`
+	}{
+		bankAccount: bankAccount(b),
+	}
+	if b.BankUUID != uuid.Nil {
+		x.BankUUID = &b.BankUUID
+	}
+	if !b.OpeningBalance.IsZero() {
+		x.OpeningBalance = &b.OpeningBalance
+	}
+	return json.Marshal(x)
+}
 
-	// decode the response
-	_, e = s.serv.Decode(r, &res)
-	if e != nil {
-		return e
+// Label returns the display name of the bank account at the bank passed to
+// the function, which is the name of the bank, the name or type of the bank
+// account and the last four digits of the account number, for example
+// "FNB Cheque ••8911".
+func (b BankAccount) Label(bank Bank) string {
+	var xs []string
+	if bank.Name != "" {
+		xs = append(xs, bank.Name)
+	}
+	if b.Name != "" {
+		xs = append(xs, b.Name)
+	} else if b.AccountType != "" {
+		xs = append(xs, b.AccountType.String())
+	}
+	n := strings.Replace(b.AccountNumber, " ", "", -1)
+	if n == "" {
+		n = b.IBAN
 	}
+	if len(n) > 4 {
+		n = n[len(n)-4:]
+	}
+	if n != "" {
+		xs = append(xs, "••"+n)
+	}
+	return strings.Join(xs, " ")
+}
+
+// BankAccountWithBank is a bank account with the bank of the bank account.
+type BankAccountWithBank struct {
+	BankAccount
+	Bank Bank
+}
+
+// Label returns the display name of the bank account at its bank, see
+// BankAccount.Label.
+func (b BankAccountWithBank) Label() string {
+	return b.BankAccount.Label(b.Bank)
+}
 
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
-		return e
+// JoinBanks attaches the bank of each bank account from the banks passed to
+// the function, for example the banks from GetBanks. The bank accounts keep
+// their order. A bank account of which the bank is not one of the banks has
+// the zero Bank.
+func JoinBanks(xba BankAccounts, xb Banks) []BankAccountWithBank {
+	bi := NewBankIndex(xb)
+	xj := make([]BankAccountWithBank, 0, len(xba))
+	for _, ba := range xba {
+		bank, _ := bi.Bank(ba.BankUUID)
+		xj = append(xj, BankAccountWithBank{
+			BankAccount: ba,
+			Bank:        bank,
+		})
 	}
-	return nil
+	return xj
 }
@@ -1,6 +1,7 @@
 package bankserv
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
@@ -479,3 +480,280 @@ func TestService_DeleteBankAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestService_GetBankAccount(t *testing.T) {
+	tt := []struct {
+		name         string
+		exchange     *microtest.Exchange
+		eBankAccount BankAccount
+		status       int
+		e            dutil.Error
+	}{
+		{
+			name: "bank account not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"bank_account":["not found"]}}`,
+				},
+			},
+			eBankAccount: BankAccount{},
+			status:       404,
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"bank_account": {"not found"},
+				},
+			},
+		},
+		{
+			name: "no bank account in response",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank account found","data":{},"errors":{}}`,
+				},
+			},
+			eBankAccount: BankAccount{},
+			status:       404,
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"bank_account": {"not found"},
+				},
+			},
+		},
+		{
+			name: "bank account found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank account found","data":{"bank_account":{"uuid":"e6b7f986-307c-4147-a34e-f924790799bb","user_uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","organisation_uuid":null,"account_number":"098765432109","active":true,"create_date":"2022-06-17T21:57:12.000Z","update_date":"2022-06-17T21:57:12.000Z"}},"errors":{}}`,
+				},
+			},
+			eBankAccount: BankAccount{
+				UUID:          uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb"),
+				UserUUID:      uuid.MustParse("e4bd194d-41e7-4f27-a4a8-161685a9b8b8"),
+				AccountNumber: "098765432109",
+				Active:        true,
+				CreateDate:    timeMustParse("2022-06-17T21:57:12.000Z"),
+				UpdateDate:    timeMustParse("2022-06-17T21:57:12.000Z"),
+			},
+			e: nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	UUID := uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb")
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			b, e := s.GetBankAccount(UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && dutil.Inst(e).Status != tc.status {
+				t.Errorf("expected status %d got %d", tc.status, dutil.Inst(e).Status)
+			}
+			if b != tc.eBankAccount {
+				t.Errorf("expected bank account %v got %v", tc.eBankAccount, b)
+			}
+			q := tc.exchange.Request.URL.Query().Get("uuid")
+			if q != UUID.String() {
+				t.Errorf("expected query uuid %s got %s", UUID.String(), q)
+			}
+		})
+	}
+}
+
+func TestBankAccount_UnmarshalJSON(t *testing.T) {
+	tt := []struct {
+		name        string
+		body        string
+		bankAccount BankAccount
+	}{
+		{
+			name: "default currency",
+			body: `{"uuid":"e6b7f986-307c-4147-a34e-f924790799bb","account_type":"cheque","currency":"","name":"","opening_balance":1500.5,"account_number":"62001238911"}`,
+			bankAccount: BankAccount{
+				UUID:           userBankAccount.UUID,
+				AccountType:    AccountTypeCheque,
+				OpeningBalance: Money{MinorUnits: 150050},
+				AccountNumber:  "62001238911",
+			},
+		},
+		{
+			name: "currency of the bank account",
+			body: `{"opening_balance":"1500","currency":"JPY","account_type":"savings","name":"Yen"}`,
+			bankAccount: BankAccount{
+				AccountType:    AccountTypeSavings,
+				Currency:       "JPY",
+				Name:           "Yen",
+				OpeningBalance: Money{MinorUnits: 1500, Currency: "JPY"},
+			},
+		},
+		{
+			name: "no opening balance",
+			body: `{"currency":"USD","opening_balance":null}`,
+			bankAccount: BankAccount{
+				Currency:       "USD",
+				OpeningBalance: Money{Currency: "USD"},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ba := BankAccount{}
+			err := json.Unmarshal([]byte(tc.body), &ba)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if ba != tc.bankAccount {
+				t.Errorf("expected bank account %v got %v", tc.bankAccount, ba)
+			}
+		})
+	}
+}
+
+func TestBankAccount_MarshalJSON(t *testing.T) {
+	tt := []struct {
+		name        string
+		bankAccount BankAccount
+		keys        []string
+		omitted     []string
+	}{
+		{
+			name:        "not set",
+			bankAccount: BankAccount{AccountNumber: "62001238911"},
+			keys:        []string{"uuid", "user_uuid", "organisation_uuid", "account_number", "active"},
+			omitted:     []string{"bank_uuid", "account_type", "currency", "name", "opening_balance", "iban"},
+		},
+		{
+			name: "set",
+			bankAccount: BankAccount{
+				BankUUID:       uuid.MustParse("3b2d2b47-8a2d-4f2b-9a75-7c4b4e0a6a11"),
+				AccountType:    AccountTypeSavings,
+				Currency:       "JPY",
+				Name:           "Yen",
+				OpeningBalance: NewMoney(1500, "JPY"),
+				AccountNumber:  "62001238911",
+			},
+			keys: []string{"bank_uuid", "account_type", "currency", "name", "opening_balance"},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			xb, err := json.Marshal(tc.bankAccount)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			m := map[string]json.RawMessage{}
+			if err := json.Unmarshal(xb, &m); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			for _, k := range tc.keys {
+				if _, ok := m[k]; !ok {
+					t.Errorf("expected key %s in %s", k, xb)
+				}
+			}
+			for _, k := range tc.omitted {
+				if _, ok := m[k]; ok {
+					t.Errorf("expected no key %s in %s", k, xb)
+				}
+			}
+			ba := BankAccount{}
+			if err := json.Unmarshal(xb, &ba); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if ba != tc.bankAccount {
+				t.Errorf("expected bank account %v got %v", tc.bankAccount, ba)
+			}
+		})
+	}
+}
+
+func TestBankAccount_Label(t *testing.T) {
+	fnb := Bank{Name: "FNB"}
+	tt := []struct {
+		name        string
+		bankAccount BankAccount
+		bank        Bank
+		label       string
+	}{
+		{
+			name:        "account type",
+			bankAccount: BankAccount{AccountType: AccountTypeCheque, AccountNumber: "62001238911"},
+			bank:        fnb,
+			label:       "FNB Cheque ••8911",
+		},
+		{
+			name:        "name",
+			bankAccount: BankAccount{Name: "Salary", AccountType: AccountTypeCheque, AccountNumber: "6200 123 8911"},
+			bank:        fnb,
+			label:       "FNB Salary ••8911",
+		},
+		{
+			name:        "credit card",
+			bankAccount: BankAccount{AccountType: AccountTypeCreditCard, AccountNumber: "4901"},
+			bank:        fnb,
+			label:       "FNB Credit Card ••4901",
+		},
+		{
+			name:        "iban",
+			bankAccount: BankAccount{AccountType: AccountTypeSavings, IBAN: "GB82WEST12345698765432"},
+			bank:        Bank{Name: "Barclays"},
+			label:       "Barclays Savings ••5432",
+		},
+		{
+			name:        "cash without bank",
+			bankAccount: BankAccount{AccountType: AccountTypeCash},
+			bank:        Bank{},
+			label:       "Cash",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			label := tc.bankAccount.Label(tc.bank)
+			if label != tc.label {
+				t.Errorf("expected label %s got %s", tc.label, label)
+			}
+		})
+	}
+}
+
+func TestJoinBanks(t *testing.T) {
+	fnb := Bank{UUID: uuid.MustParse("0c5f6e4a-7d1b-4a8e-9f2c-3b6d8e1a4c70"), Name: "FNB", BranchCode: "250655"}
+	xb := Banks{investec, fnb}
+	xba := BankAccounts{
+		{UUID: uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb"), BankUUID: fnb.UUID, AccountType: AccountTypeCheque, AccountNumber: "62001238911"},
+		{UUID: uuid.MustParse("1d1f0b8e-6a3c-4f7e-8a2b-5c9d0e1f2a3b"), BankUUID: uuid.MustParse("9e3f2a1b-0c4d-4e5f-8a6b-7c8d9e0f1a2b")},
+		{UUID: uuid.MustParse("2a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"), BankUUID: investec.UUID, Name: "Private"},
+	}
+
+	xj := JoinBanks(xba, xb)
+	if len(xj) != 3 {
+		t.Fatalf("expected 3 bank accounts got %d", len(xj))
+	}
+	for i, j := range xj {
+		if j.UUID != xba[i].UUID {
+			t.Errorf("expected bank account %d to be %s got %s", i, xba[i].UUID, j.UUID)
+		}
+	}
+	if xj[0].Bank.UUID != fnb.UUID || xj[0].Label() != "FNB Cheque ••8911" {
+		t.Errorf("expected the FNB bank got %v with label %s", xj[0].Bank, xj[0].Label())
+	}
+	if xj[1].Bank != (Bank{}) {
+		t.Errorf("expected the zero bank got %v", xj[1].Bank)
+	}
+	if xj[2].Bank.UUID != investec.UUID {
+		t.Errorf("expected the investec bank got %v", xj[2].Bank)
+	}
+}
@@ -1,10 +1,12 @@
 package bankserv
 
 import (
+	"context"
 	"fmt"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
 	"github.com/johannesscr/micro/microtest"
+	"strings"
 	"testing"
 )
 
@@ -83,3 +85,451 @@ func TestService_GetBanks(t *testing.T) {
 		})
 	}
 }
+
+func TestService_GetBanksContext(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	ms.Append(&microtest.Exchange{
+		Response: microtest.Response{
+			Status: 200,
+			Body:   `{"message":"banks found","data":{"banks":[]},"errors":{}}`,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	xb, e := s.GetBanksContext(ctx)
+	ee := dutil.NewErr(499, "context", []string{"context canceled"})
+	if !dutil.ErrorEqual(ee, e) {
+		t.Errorf("expected error %v got %v", ee, e)
+	}
+	if len(xb) != 0 {
+		t.Errorf("expected no banks got %v", xb)
+	}
+
+	xb, e = s.GetBanksContext(context.Background())
+	if e != nil {
+		t.Errorf("unexpected error %v", e)
+	}
+	if len(xb) != 0 {
+		t.Errorf("expected no banks got %v", xb)
+	}
+}
+
+var investec = Bank{
+	UUID:       uuid.MustParse("2955f13a-f331-4c28-b007-1fc658a61b30"),
+	Name:       "investec",
+	BranchCode: "580105",
+	Active:     true,
+	CreateDate: timeMustParse("2022-01-01T12:00:00Z"),
+	UpdateDate: timeMustParse("2022-01-01T12:00:00Z"),
+}
+
+const investecJSON = `{"uuid":"2955f13a-f331-4c28-b007-1fc658a61b30","name":"investec","branch_code":"580105","active":true,"create_date":"2022-01-01T12:00:00Z","update_date":"2022-01-01T12:00:00Z"}`
+
+func TestService_GetBank(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		bank     Bank
+		e        dutil.Error
+	}{
+		{
+			name: "bank not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"bank":["not found"]}}`,
+				},
+			},
+			bank: Bank{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"bank": {"not found"},
+				},
+			},
+		},
+		{
+			name: "no bank in response",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank found","data":{},"errors":{}}`,
+				},
+			},
+			bank: Bank{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"bank": {"not found"},
+				},
+			},
+		},
+		{
+			name: "bank found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank found","data":{"bank":` + investecJSON + `},"errors":{}}`,
+				},
+			},
+			bank: investec,
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			b, e := s.GetBank(investec.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if b != tc.bank {
+				t.Errorf("expected bank %v got %v", tc.bank, b)
+			}
+			q := tc.exchange.Request.URL.Query().Get("uuid")
+			if q != investec.UUID.String() {
+				t.Errorf("expected query uuid %s got %s", investec.UUID.String(), q)
+			}
+		})
+	}
+}
+
+func TestService_GetBankByBranchCode(t *testing.T) {
+	tt := []struct {
+		name       string
+		branchCode string
+		exchange   *microtest.Exchange
+		bank       Bank
+		e          dutil.Error
+	}{
+		{
+			name:       "bank not found",
+			branchCode: "000000",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"bank":["not found"]}}`,
+				},
+			},
+			bank: Bank{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"bank": {"not found"},
+				},
+			},
+		},
+		{
+			name:       "no bank in response",
+			branchCode: "000000",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank found","data":{},"errors":{}}`,
+				},
+			},
+			bank: Bank{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"bank": {"not found"},
+				},
+			},
+		},
+		{
+			name:       "bank found",
+			branchCode: " 580105 ",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank found","data":{"bank":` + investecJSON + `},"errors":{}}`,
+				},
+			},
+			bank: investec,
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			b, e := s.GetBankByBranchCode(tc.branchCode)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if b != tc.bank {
+				t.Errorf("expected bank %v got %v", tc.bank, b)
+			}
+			q := tc.exchange.Request.URL.Query().Get("branch_code")
+			if q != strings.TrimSpace(tc.branchCode) {
+				t.Errorf("expected query branch_code %s got %s", tc.branchCode, q)
+			}
+		})
+	}
+}
+
+func TestService_CreateBank(t *testing.T) {
+	tt := []struct {
+		name     string
+		bank     Bank
+		exchange *microtest.Exchange
+		EBank    Bank
+		e        dutil.Error
+	}{
+		{
+			name: "permission required",
+			bank: Bank{Name: "investec", BranchCode: "580105"},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body:   `{"message":"Forbidden: Unable to process request","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+				},
+			},
+			EBank: Bank{},
+			e: &dutil.Err{
+				Status: 403,
+				Errors: map[string][]string{
+					"permission": {"Please ensure you have permission"},
+				},
+			},
+		},
+		{
+			name: "create bank",
+			bank: Bank{Name: "investec", BranchCode: "580105"},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 201,
+					Body:   `{"message":"bank created","data":{"bank":` + investecJSON + `},"errors":{}}`,
+				},
+			},
+			EBank: investec,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			b, e := s.CreateBank(tc.bank)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if b != tc.EBank {
+				t.Errorf("expected bank %v got %v", tc.EBank, b)
+			}
+		})
+	}
+}
+
+func TestService_UpdateBank(t *testing.T) {
+	tt := []struct {
+		name     string
+		bank     Bank
+		exchange *microtest.Exchange
+		EBank    Bank
+		e        dutil.Error
+	}{
+		{
+			name: "bad request",
+			bank: Bank{},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 400,
+					Body:   `{"message":"BadRequest: Unable to process request","data":{},"errors":{"uuid":["required field"]}}`,
+				},
+			},
+			EBank: Bank{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"uuid": {"required field"},
+				},
+			},
+		},
+		{
+			name: "update bank",
+			bank: investec,
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank updated","data":{"bank":` + investecJSON + `},"errors":{}}`,
+				},
+			},
+			EBank: investec,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			b, e := s.UpdateBank(tc.bank)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if b != tc.EBank {
+				t.Errorf("expected bank %v got %v", tc.EBank, b)
+			}
+		})
+	}
+}
+
+func TestService_DeleteBank(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		e        dutil.Error
+	}{
+		{
+			name: "permission required",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body:   `{"message":"Forbidden: Unable to process request","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+				},
+			},
+			e: &dutil.Err{
+				Status: 403,
+				Errors: map[string][]string{
+					"permission": {"Please ensure you have permission"},
+				},
+			},
+		},
+		{
+			name: "bank deleted",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank deleted","data":{},"errors":{}}`,
+				},
+			},
+			e: nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			e := s.DeleteBank(investec.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+		})
+	}
+}
+
+func TestBankIndex(t *testing.T) {
+	fnb := Bank{
+		UUID:       uuid.MustParse("7c0a3b3d-7e42-4a43-8f1e-5b0a2b7f6c11"),
+		Name:       "fnb",
+		BranchCode: "250655",
+	}
+	standard := Bank{
+		UUID:       uuid.MustParse("0e6a51e5-5b1a-4b0f-9d0c-8f6c2a0e1d22"),
+		Name:       "standard bank",
+		BranchCode: "051001",
+	}
+	bi := NewBankIndex(Banks{investec, fnb, standard})
+
+	if bi.Len() != 3 {
+		t.Errorf("expected index length %d got %d", 3, bi.Len())
+	}
+
+	tt := []struct {
+		name       string
+		branchCode string
+		bank       Bank
+		ok         bool
+	}{
+		{
+			name:       "unknown branch code",
+			branchCode: "123456",
+			bank:       Bank{},
+			ok:         false,
+		},
+		{
+			name:       "branch code",
+			branchCode: "250655",
+			bank:       fnb,
+			ok:         true,
+		},
+		{
+			name:       "branch code with spaces",
+			branchCode: " 580105",
+			bank:       investec,
+			ok:         true,
+		},
+		{
+			name:       "branch code without leading zero",
+			branchCode: "51001",
+			bank:       standard,
+			ok:         true,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			b, ok := bi.BranchCode(tc.branchCode)
+			if ok != tc.ok {
+				t.Errorf("expected ok %t got %t", tc.ok, ok)
+			}
+			if b != tc.bank {
+				t.Errorf("expected bank %v got %v", tc.bank, b)
+			}
+		})
+	}
+
+	b, ok := bi.Bank(fnb.UUID)
+	if !ok || b != fnb {
+		t.Errorf("expected bank %v got %v", fnb, b)
+	}
+}
+
+func TestService_GetBankIndex(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	ms.Append(&microtest.Exchange{
+		Response: microtest.Response{
+			Status: 200,
+			Body:   `{"message":"banks found","data":{"banks":[` + investecJSON + `]},"errors":{}}`,
+		},
+	})
+
+	bi, e := s.GetBankIndex()
+	if e != nil {
+		t.Errorf("unexpected error %v", e)
+	}
+	b, ok := bi.BranchCode("580105")
+	if !ok || b != investec {
+		t.Errorf("expected bank %v got %v", investec, b)
+	}
+}
@@ -0,0 +1,87 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"strings"
+)
+
+// countryCodes are the ISO 3166-1 alpha-2 country codes, and XK which SWIFT
+// uses for Kosovo.
+var countryCodes = stringSet(strings.Fields(`
+	AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ
+	BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+	CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ
+	DE DJ DK DM DO DZ
+	EC EE EG EH ER ES ET
+	FI FJ FK FM FO FR
+	GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY
+	HK HM HN HR HT HU
+	ID IE IL IM IN IO IQ IR IS IT
+	JE JM JO JP
+	KE KG KH KI KM KN KP KR KW KY KZ
+	LA LB LC LI LK LR LS LT LU LV LY
+	MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ
+	NA NC NE NF NG NI NL NO NP NR NU NZ
+	OM
+	PA PE PF PG PH PK PL PM PN PR PS PT PW PY
+	QA
+	RE RO RS RU RW
+	SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ
+	TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ
+	UA UG UM US UY UZ
+	VA VC VE VG VI VN VU
+	WF WS
+	XK
+	YE YT
+	ZA ZM ZW
+`))
+
+func stringSet(xs []string) map[string]bool {
+	m := make(map[string]bool, len(xs))
+	for _, s := range xs {
+		m[s] = true
+	}
+	return m
+}
+
+// ParseBIC parses a Business Identifier Code (BIC), also known as a SWIFT
+// code, and returns the BIC in upper case without spaces, for example
+// "FIRNZAJJ" or "FIRNZAJJXXX".
+//
+// A BIC has 8 or 11 characters: the 4 letter institution code, the 2 letter
+// ISO 3166-1 country code, the 2 character location code and the optional 3
+// character branch code. If the BIC is invalid an error of the kind
+// ErrValidation with the key "bic" is returned.
+func ParseBIC(s string) (string, dutil.Error) {
+	bic := strings.ToUpper(strings.Replace(strings.TrimSpace(s), " ", "", -1))
+	reason := bicReason(bic)
+	if reason != "" {
+		v := validation{}
+		v.add("bic", reason)
+		return "", v.err()
+	}
+	return bic, nil
+}
+
+// bicReason returns the reason the BIC is invalid, or an empty string if the
+// BIC is valid.
+func bicReason(bic string) string {
+	if len(bic) != 8 && len(bic) != 11 {
+		return "must be 8 or 11 characters"
+	}
+	for i := 0; i < len(bic); i++ {
+		c := bic[i]
+		letter := c >= 'A' && c <= 'Z'
+		if i < 6 && !letter {
+			return "institution and country code must only contain letters"
+		}
+		if !letter && !isDigit(c) {
+			return "must only contain letters and digits"
+		}
+	}
+	if !countryCodes[bic[4:6]] {
+		return fmt.Sprintf("unknown country '%s'", bic[4:6])
+	}
+	return ""
+}
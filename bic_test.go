@@ -0,0 +1,68 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"testing"
+)
+
+func TestParseBIC(t *testing.T) {
+	tt := []struct {
+		name string
+		s    string
+		bic  string
+		e    dutil.Error
+	}{
+		{
+			name: "8 characters",
+			s:    "FIRNZAJJ",
+			bic:  "FIRNZAJJ",
+			e:    nil,
+		},
+		{
+			name: "11 characters",
+			s:    "firnzajjxxx",
+			bic:  "FIRNZAJJXXX",
+			e:    nil,
+		},
+		{
+			name: "location with digits",
+			s:    "DEUTDEFF500",
+			bic:  "DEUTDEFF500",
+			e:    nil,
+		},
+		{
+			name: "length",
+			s:    "FIRNZAJJX",
+			e:    dutil.NewErr(400, "bic", []string{"must be 8 or 11 characters"}),
+		},
+		{
+			name: "digit in institution code",
+			s:    "F1RNZAJJ",
+			e:    dutil.NewErr(400, "bic", []string{"institution and country code must only contain letters"}),
+		},
+		{
+			name: "invalid characters",
+			s:    "FIRNZAJ-",
+			e:    dutil.NewErr(400, "bic", []string{"must only contain letters and digits"}),
+		},
+		{
+			name: "unknown country",
+			s:    "FIRNZZJJ",
+			e:    dutil.NewErr(400, "bic", []string{"unknown country 'ZZ'"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			bic, e := ParseBIC(tc.s)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if bic != tc.bic {
+				t.Errorf("expected BIC %s got %s", tc.bic, bic)
+			}
+		})
+	}
+}
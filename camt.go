@@ -0,0 +1,395 @@
+package bankserv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/dottics/dutil"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// camtDocument is the part of a camt.052, camt.053 or camt.054 document
+// which is read by ParseCAMT. The elements are matched by their local name,
+// such that every version of the messages is read.
+type camtDocument struct {
+	Statements    []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports       []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+	Notifications []camtStatement `xml:"BkToCstmrDbtCdtNtfctn>Ntfctn"`
+}
+
+type camtStatement struct {
+	ID         string        `xml:"Id"`
+	SequenceNb string        `xml:"ElctrncSeqNb"`
+	LegalSeqNb string        `xml:"LglSeqNb"`
+	IBAN       string        `xml:"Acct>Id>IBAN"`
+	OtherID    string        `xml:"Acct>Id>Othr>Id"`
+	Currency   string        `xml:"Acct>Ccy"`
+	Balances   []camtBalance `xml:"Bal"`
+	Entries    []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference      string     `xml:"NtryRef"`
+	Amount         camtAmount `xml:"Amt"`
+	Indicator      string     `xml:"CdtDbtInd"`
+	Reversal       bool       `xml:"RvslInd"`
+	Status         camtStatus `xml:"Sts"`
+	BookingDate    camtDate   `xml:"BookgDt"`
+	ValueDate      camtDate   `xml:"ValDt"`
+	ServicerRef    string     `xml:"AcctSvcrRef"`
+	Details        []camtTx   `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string     `xml:"AddtlNtryInf"`
+}
+
+type camtTx struct {
+	EndToEndID   string     `xml:"Refs>EndToEndId"`
+	Amount       camtAmount `xml:"Amt"`
+	TxAmount     camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Indicator    string     `xml:"CdtDbtInd"`
+	Unstructured []string   `xml:"RmtInf>Ustrd"`
+	Structured   []string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	// the name of a party is in Pty from version 8 of the messages
+	DebtorName    string `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPtyName string `xml:"RltdPties>Dbtr>Pty>Nm"`
+	CreditorName  string `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty   string `xml:"RltdPties>Cdtr>Pty>Nm"`
+	AdditionalInf string `xml:"AddtlTxInf"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is the status of an entry, which is the text of the element
+// before version 8 of the messages and the Cd of the element from version 8.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+// camtDate is a date, Dt, or a date and time, DtTm, of a camt message.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// ParseCAMT reads the statements of an ISO 20022 camt.053 bank to customer
+// statement, a camt.052 intraday account report or a camt.054 notification.
+//
+// Every booked entry, Ntry, is a transaction with the booking date as its
+// Date and the value date as its ValueDate. The AcctSvcrRef of the entry, or
+// else the NtryRef, is the ExternalID. Every transaction detail, TxDtls, of
+// the entry is an item and an entry without details has a single item. The
+// amount of an item is the booked amount, Amt, of the detail, or else the
+// TxAmt of its AmtDtls if the TxAmt is in the currency of the entry. The
+// remittance information of the details, or else the additional information
+// of the entry or the name of the other party, is the description. A
+// reversal, RvslInd, has the opposite sign of its CdtDbtInd. Pending and
+// information entries are not read.
+//
+// The opening (OPBD or PRCD), closing (CLBD or ITBD) and available (CLAV or
+// ITAV) balances are the balances of the statement. The IBAN, or the other
+// ID, of the account of a statement is matched with the IBAN or the
+// AccountNumber of the bank accounts passed to the function to set the
+// AccountUUID of the statement and its transactions.
+//
+// A document which is not able to be read returns an error of the kind
+// ErrValidation with the key "camt". The entries which are not able to be
+// parsed are skipped and reported in an error keyed by the number of the
+// entry in the document, for example "entry 3", such that the statements
+// are still returned.
+func ParseCAMT(r io.Reader, xba BankAccounts) ([]Statement, dutil.Error) {
+	doc := camtDocument{}
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = camtCharsetReader
+	if err := dec.Decode(&doc); err != nil {
+		return nil, newError(dutil.NewErr(400, "camt", []string{err.Error()}))
+	}
+	all := append(append(doc.Statements, doc.Reports...), doc.Notifications...)
+	if len(all) == 0 {
+		return nil, newError(dutil.NewErr(400, "camt", []string{"no statement, report or notification"}))
+	}
+
+	v := validation{}
+	n := 0
+	xs := make([]Statement, 0, len(all))
+	for _, cs := range all {
+		st := Statement{
+			Reference:     strings.TrimSpace(cs.ID),
+			Number:        strings.TrimSpace(cs.SequenceNb),
+			IBAN:          strings.TrimSpace(cs.IBAN),
+			AccountNumber: strings.TrimSpace(cs.OtherID),
+			Currency:      strings.ToUpper(strings.TrimSpace(cs.Currency)),
+			Transactions:  Transactions{},
+		}
+		if st.Number == "" {
+			st.Number = strings.TrimSpace(cs.LegalSeqNb)
+		}
+		if iban, e := ParseIBAN(st.IBAN); e == nil {
+			st.IBAN = iban
+		}
+		if st.AccountNumber == "" {
+			st.AccountNumber = st.IBAN
+		}
+		camtBalances(&st, cs.Balances, &v)
+
+		for _, ce := range cs.Entries {
+			n++
+			switch ce.Status.code() {
+			case "PDNG", "INFO":
+				continue
+			}
+			t, reasons := ce.transaction(st.Currency)
+			if len(reasons) > 0 {
+				v.add(fmt.Sprintf("entry %d", n), reasons...)
+				continue
+			}
+			st.Transactions = append(st.Transactions, t)
+		}
+		st.matchBankAccount(xba)
+		xs = append(xs, st)
+	}
+	return xs, v.err()
+}
+
+// camtBalances sets the balances of the statement, the first balance of each
+// type is used. If the statement has no currency the currency of the
+// balances is used.
+func camtBalances(st *Statement, xb []camtBalance, v *validation) {
+	// the balances of the end of the day take precedence over the previous
+	// closing and the interim balances
+	passes := []map[string]**StatementBalance{
+		{"OPBD": &st.Opening, "CLBD": &st.Closing, "CLAV": &st.Available},
+		{"PRCD": &st.Opening, "ITBD": &st.Closing, "ITAV": &st.Available},
+	}
+	for _, targets := range passes {
+		for _, b := range xb {
+			code := strings.ToUpper(strings.TrimSpace(b.Code))
+			target, ok := targets[code]
+			if !ok || *target != nil {
+				continue
+			}
+			amount, reason := b.Amount.money(b.Indicator, false)
+			if reason != "" {
+				v.add(strings.ToLower(code), reason)
+				continue
+			}
+			date, ok := b.Date.time()
+			if !ok {
+				v.add(strings.ToLower(code), fmt.Sprintf("invalid date '%s'", b.Date.Date+b.Date.DateTime))
+				continue
+			}
+			if st.Currency == "" {
+				st.Currency = amount.Currency
+			}
+			*target = &StatementBalance{Amount: amount, Date: date}
+		}
+	}
+}
+
+// code returns the code of the status.
+func (s camtStatus) code() string {
+	if c := strings.TrimSpace(s.Code); c != "" {
+		return strings.ToUpper(c)
+	}
+	return strings.ToUpper(strings.TrimSpace(s.Value))
+}
+
+// transaction returns the transaction of the entry, or the reasons the entry
+// is not able to be parsed.
+func (ce camtEntry) transaction(currency string) (Transaction, []string) {
+	var reasons []string
+	date, ok := ce.BookingDate.time()
+	if !ok {
+		// an entry which is not booked yet has no booking date
+		date, ok = ce.ValueDate.time()
+		if !ok {
+			reasons = append(reasons, "no booking date")
+		}
+	}
+	var valueDate *time.Time
+	if t, ok := ce.ValueDate.time(); ok {
+		valueDate = &t
+	}
+	if ce.Amount.Currency == "" {
+		ce.Amount.Currency = currency
+	}
+	amount, reason := ce.Amount.money(ce.Indicator, ce.Reversal)
+	if reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	items := make(Items, 0, len(ce.Details))
+	var remittance []string
+	for _, tx := range ce.Details {
+		info := tx.remittance()
+		if info != "" {
+			remittance = append(remittance, info)
+		}
+		// the Amt of the detail is the booked amount, the TxAmt is the amount
+		// of the instruction, which may be in another currency
+		a := tx.Amount
+		if strings.TrimSpace(a.Value) == "" {
+			if c := strings.TrimSpace(tx.TxAmount.Currency); c == "" || strings.EqualFold(c, ce.Amount.Currency) {
+				a = tx.TxAmount
+			}
+		}
+		var ia Money
+		if strings.TrimSpace(a.Value) == "" && len(ce.Details) == 1 {
+			// a single detail without an amount has the amount of the entry
+			ia = amount
+		} else {
+			if a.Currency == "" {
+				a.Currency = ce.Amount.Currency
+			}
+			indicator := tx.Indicator
+			if indicator == "" {
+				indicator = ce.Indicator
+			}
+			var reason string
+			ia, reason = a.money(indicator, ce.Reversal)
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+		if info == "" {
+			info = tx.party(amount)
+		}
+		items = append(items, Item{
+			Description: info,
+			SKU:         Units(1),
+			Amount:      ia,
+			Active:      true,
+		})
+	}
+	if len(reasons) > 0 {
+		return Transaction{}, reasons
+	}
+
+	description := strings.Join(remittance, " ")
+	if description == "" {
+		description = strings.TrimSpace(ce.AdditionalInfo)
+	}
+	if description == "" && len(items) > 0 {
+		description = items[0].Description
+	}
+	if len(items) == 0 {
+		items = Items{{Description: description, SKU: Units(1), Amount: amount, Active: true}}
+	}
+	externalID := strings.TrimSpace(ce.ServicerRef)
+	if externalID == "" {
+		externalID = strings.TrimSpace(ce.Reference)
+	}
+	return Transaction{
+		ExternalID:  externalID,
+		Date:        date,
+		ValueDate:   valueDate,
+		Description: description,
+		Items:       items,
+		Active:      true,
+	}, nil
+}
+
+// remittance returns the unstructured remittance information of the
+// transaction, or else the structured creditor references, joined by spaces.
+func (tx camtTx) remittance() string {
+	xs := tx.Unstructured
+	if len(xs) == 0 {
+		xs = tx.Structured
+	}
+	parts := make([]string, 0, len(xs))
+	for _, s := range xs {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// party returns the name of the other party of the transaction, the creditor
+// of a debit and the debtor of a credit, or the additional information of the
+// transaction if the name is not known.
+func (tx camtTx) party(amount Money) string {
+	names := []string{tx.DebtorName, tx.DebtorPtyName}
+	if amount.Sign() < 0 {
+		names = []string{tx.CreditorName, tx.CreditorPty}
+	}
+	for _, n := range append(names, tx.AdditionalInf) {
+		if n = strings.TrimSpace(n); n != "" {
+			return n
+		}
+	}
+	return ""
+}
+
+// money returns the signed amount with the credit or debit indicator, CRDT
+// or DBIT, or the reason the amount is not able to be parsed. The sign of a
+// reversal is the opposite of its indicator.
+func (a camtAmount) money(indicator string, reversal bool) (Money, string) {
+	m, e := ParseMoney(strings.TrimSpace(a.Value), strings.TrimSpace(a.Currency))
+	if e != nil {
+		return Money{}, fmt.Sprintf("invalid amount '%s'", a.Value)
+	}
+	debit := false
+	switch strings.ToUpper(strings.TrimSpace(indicator)) {
+	case "DBIT":
+		debit = true
+	case "CRDT":
+	default:
+		return Money{}, fmt.Sprintf("invalid credit or debit indicator '%s'", indicator)
+	}
+	if debit != reversal {
+		m = m.Neg()
+	}
+	return m, ""
+}
+
+// time returns the date or date and time, or false if neither is set or the
+// date is not able to be parsed. A date and time without an offset is in
+// UTC.
+func (d camtDate) time() (time.Time, bool) {
+	if s := strings.TrimSpace(d.Date); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		return t, err == nil
+	}
+	s := strings.TrimSpace(d.DateTime)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// camtCharsetReader returns a reader of the document in UTF-8 for the
+// ISO 8859-1 and Windows-1252 encodings, which are the encodings used apart
+// from UTF-8.
+func camtCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	var enc Encoding
+	switch strings.ToLower(label) {
+	case "iso-8859-1", "iso8859-1", "latin1":
+		enc = Latin1
+	case "windows-1252", "cp1252":
+		enc = Windows1252
+	default:
+		return nil, fmt.Errorf("unsupported encoding '%s'", label)
+	}
+	xb, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(enc.decode(xb)), nil
+}
@@ -0,0 +1,334 @@
+package bankserv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+)
+
+const camt053Statement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG220630</MsgId><CreDtTm>2022-07-01T06:00:00</CreDtTm></GrpHdr>
+    <Stmt>
+      <Id>STMT220630</Id>
+      <ElctrncSeqNb>6</ElctrncSeqNb>
+      <Acct><Id><Othr><Id>1234567890</Id></Othr></Id><Ccy>ZAR</Ccy></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>PRCD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="ZAR">9999.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2022-05-31</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="ZAR">10000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2022-06-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="ZAR">24758.81</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2022-06-30</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLAV</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="ZAR">24700.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2022-06-30</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <NtryRef>1</NtryRef>
+        <Amt Ccy="ZAR">236.19</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2022-06-18</Dt></BookgDt>
+        <ValDt><Dt>2022-06-17</Dt></ValDt>
+        <AcctSvcrRef>FT22169ABC</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <RltdPties><Cdtr><Nm>SUPERSPAR</Nm></Cdtr></RltdPties>
+            <RmtInf><Ustrd>SUPERSPAR JEFFREYS BAY</Ustrd><Ustrd>EASTERN CAPE ZA</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <NtryRef>2</NtryRef>
+        <Amt Ccy="ZAR">15000.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2022-06-25</Dt></BookgDt>
+        <ValDt><Dt>2022-06-25</Dt></ValDt>
+        <NtryDtls>
+          <TxDtls>
+            <Amt Ccy="ZAR">10000.00</Amt>
+            <CdtDbtInd>CRDT</CdtDbtInd>
+            <RltdPties><Dbtr><Nm>ACME LTD</Nm></Dbtr></RltdPties>
+          </TxDtls>
+          <TxDtls>
+            <AmtDtls><TxAmt><Amt Ccy="ZAR">5000.00</Amt></TxAmt></AmtDtls>
+            <RmtInf><Strd><CdtrRefInf><Ref>INV-1042</Ref></CdtrRefInf></Strd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="ZAR">5.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2022-06-30</Dt></BookgDt>
+        <ValDt><Dt>2022-06-30</Dt></ValDt>
+        <AcctSvcrRef>FEE0630</AcctSvcrRef>
+        <AddtlNtryInf>MONTHLY FEE</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="ZAR">99.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <ValDt><Dt>2022-07-01</Dt></ValDt>
+        <AddtlNtryInf>CARD AUTHORISATION</AddtlNtryInf>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+const camt052Report = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.052.001.08">
+  <BkToCstmrAcctRpt>
+    <Rpt>
+      <Id>RPT220615</Id>
+      <Acct><Id><IBAN>GB82 WEST 1234 5698 7654 32</IBAN></Id></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>ITBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="GBP">0.50</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><DtTm>2022-06-15T14:00:00+01:00</DtTm></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="GBP">100.50</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <RvslInd>true</RvslInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2022-06-15T09:30:00+01:00</DtTm></BookgDt>
+        <ValDt><Dt>2022-06-15</Dt></ValDt>
+        <AcctSvcrRef>456</AcctSvcrRef>
+        <AddtlNtryInf>RETURNED PAYMENT</AddtlNtryInf>
+      </Ntry>
+    </Rpt>
+  </BkToCstmrAcctRpt>
+</Document>
+`
+
+func TestParseCAMT(t *testing.T) {
+	organisation := organisationBankAccount
+	organisation.AccountNumber = "1234567890"
+
+	xs, e := ParseCAMT(strings.NewReader(camt053Statement), BankAccounts{userBankAccount, organisation})
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if len(xs) != 1 {
+		t.Fatalf("expected 1 statement got %d", len(xs))
+	}
+
+	st := xs[0]
+	if st.Reference != "STMT220630" || st.Number != "6" || st.AccountNumber != "1234567890" || st.Currency != "ZAR" || st.AccountUUID != organisation.UUID {
+		t.Errorf("expected the statement of %s got %v", organisation.UUID, st)
+	}
+	if st.Opening == nil || st.Opening.Amount != NewMoney(1000000, "ZAR") || !st.Opening.Date.Equal(timeMustParse("2022-06-01T00:00:00Z")) {
+		t.Errorf("expected the booked opening balance 10000.00 got %v", st.Opening)
+	}
+	if st.Closing == nil || st.Closing.Amount != NewMoney(2475881, "ZAR") {
+		t.Errorf("expected closing balance 24758.81 got %v", st.Closing)
+	}
+	if st.Available == nil || st.Available.Amount != NewMoney(2470000, "ZAR") {
+		t.Errorf("expected available balance 24700.00 got %v", st.Available)
+	}
+	expected := Transactions{
+		{
+			AccountUUID: organisation.UUID,
+			ExternalID:  "FT22169ABC",
+			Date:        timeMustParse("2022-06-18T00:00:00Z"),
+			ValueDate:   timeMustParsePtr("2022-06-17T00:00:00Z"),
+			Description: "SUPERSPAR JEFFREYS BAY EASTERN CAPE ZA",
+			Items: Items{
+				{Description: "SUPERSPAR JEFFREYS BAY EASTERN CAPE ZA", SKU: Units(1), Amount: NewMoney(-23619, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+		{
+			AccountUUID: organisation.UUID,
+			ExternalID:  "2",
+			Date:        timeMustParse("2022-06-25T00:00:00Z"),
+			ValueDate:   timeMustParsePtr("2022-06-25T00:00:00Z"),
+			Description: "INV-1042",
+			Items: Items{
+				{Description: "ACME LTD", SKU: Units(1), Amount: NewMoney(1000000, "ZAR"), Active: true},
+				{Description: "INV-1042", SKU: Units(1), Amount: NewMoney(500000, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+		{
+			AccountUUID: organisation.UUID,
+			ExternalID:  "FEE0630",
+			Date:        timeMustParse("2022-06-30T00:00:00Z"),
+			ValueDate:   timeMustParsePtr("2022-06-30T00:00:00Z"),
+			Description: "MONTHLY FEE",
+			Items: Items{
+				{Description: "MONTHLY FEE", SKU: Units(1), Amount: NewMoney(-500, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+	}
+	if !EqualTransactions(expected, st.Transactions) {
+		t.Errorf("expected %v got %v", expected, st.Transactions)
+	}
+	if e := st.checkBalance(); e != nil {
+		t.Errorf("expected the statement to balance got %v", e)
+	}
+}
+
+func TestParseCAMT_report(t *testing.T) {
+	international := BankAccount{
+		UUID: uuid.MustParse("7a4c51ab-c87b-4fd3-8d84-b5d2e3e7b0b1"),
+		IBAN: "GB82WEST12345698765432",
+	}
+
+	xs, e := ParseCAMT(strings.NewReader(camt052Report), BankAccounts{organisationBankAccount, international})
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if len(xs) != 1 {
+		t.Fatalf("expected 1 report got %d", len(xs))
+	}
+	st := xs[0]
+	if st.Reference != "RPT220615" || st.IBAN != international.IBAN || st.AccountUUID != international.UUID || st.Currency != "GBP" {
+		t.Errorf("expected the report of %s got %v", international.UUID, st)
+	}
+	if st.Opening != nil || st.Available != nil || st.Closing == nil || st.Closing.Amount != NewMoney(50, "GBP") {
+		t.Errorf("expected only the interim booked balance got %v", st)
+	}
+	if len(st.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction got %d", len(st.Transactions))
+	}
+	tx := st.Transactions[0]
+	date := time.Date(2022, 6, 15, 9, 30, 0, 0, time.FixedZone("", 3600))
+	if !tx.Date.Equal(date) || tx.ExternalID != "456" || tx.Description != "RETURNED PAYMENT" || tx.AccountUUID != international.UUID {
+		t.Errorf("expected the reversal booked at %v got %v", date, tx)
+	}
+	if len(tx.Items) != 1 || tx.Items[0].Amount != NewMoney(-10050, "GBP") {
+		t.Errorf("expected the reversal of a credit to be a debit got %v", tx.Items)
+	}
+}
+
+func TestParseCAMT_foreignCurrency(t *testing.T) {
+	// entry returns a GBP statement with an entry of GBP 85.00 of which the
+	// instruction was EUR 100.00, with the details passed to the function
+	entry := func(details string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT220630</Id>
+      <Acct><Id><IBAN>GB82WEST12345698765432</IBAN></Id><Ccy>GBP</Ccy></Acct>
+      <Ntry>
+        <NtryRef>1</NtryRef>
+        <Amt Ccy="GBP">85.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2022-06-18</Dt></BookgDt>
+        <NtryDtls><TxDtls>` + details + `<RmtInf><Ustrd>HOTEL PARIS</Ustrd></RmtInf></TxDtls></NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+	}
+
+	tt := []struct {
+		name    string
+		details string
+		amount  Money
+	}{
+		{
+			name:    "booked amount of the detail",
+			details: `<Amt Ccy="GBP">85.00</Amt><AmtDtls><TxAmt><Amt Ccy="EUR">100.00</Amt></TxAmt></AmtDtls>`,
+			amount:  NewMoney(-8500, "GBP"),
+		},
+		{
+			name:    "instructed amount in another currency",
+			details: `<AmtDtls><TxAmt><Amt Ccy="EUR">100.00</Amt></TxAmt></AmtDtls>`,
+			amount:  NewMoney(-8500, "GBP"),
+		},
+		{
+			name:    "instructed amount in the currency of the entry",
+			details: `<AmtDtls><TxAmt><Amt Ccy="GBP">85.00</Amt></TxAmt></AmtDtls>`,
+			amount:  NewMoney(-8500, "GBP"),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			xs, e := ParseCAMT(strings.NewReader(entry(tc.details)), nil)
+			if e != nil {
+				t.Fatalf("unexpected error %v", e)
+			}
+			if len(xs) != 1 || len(xs[0].Transactions) != 1 {
+				t.Fatalf("expected 1 statement with 1 transaction got %v", xs)
+			}
+			tx := xs[0].Transactions[0]
+			if len(tx.Items) != 1 || tx.Items[0].Amount != tc.amount {
+				t.Errorf("expected an item of %v got %v", tc.amount, tx.Items)
+			}
+			net, e := tx.Net()
+			if e != nil || net != tc.amount {
+				t.Errorf("expected the net amount %v of the entry got %v %v", tc.amount, net, e)
+			}
+		})
+	}
+}
+
+func TestParseCAMT_invalid(t *testing.T) {
+	tt := []struct {
+		name     string
+		document string
+		n        int
+		e        dutil.Error
+	}{
+		{
+			name:     "not xml",
+			document: "STMT220630,1234567890",
+			e:        dutil.NewErr(400, "camt", []string{"EOF"}),
+		},
+		{
+			name:     "no statement",
+			document: `<Document><BkToCstmrDbtCdtNtfctn/></Document>`,
+			e:        dutil.NewErr(400, "camt", []string{"no statement, report or notification"}),
+		},
+		{
+			name: "invalid entries",
+			document: strings.NewReplacer(
+				"<Amt Ccy=\"ZAR\">236.19</Amt>", "<Amt Ccy=\"ZAR\">236,19</Amt>",
+				"<BookgDt><Dt>2022-06-30</Dt></BookgDt>\n        <ValDt><Dt>2022-06-30</Dt></ValDt>", "",
+				"<Amt Ccy=\"ZAR\">10000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>", "<Amt Ccy=\"ZAR\">10000.00</Amt><CdtDbtInd>C</CdtDbtInd>",
+			).Replace(camt053Statement),
+			n: 1,
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"opbd":    {"invalid credit or debit indicator 'C'"},
+					"entry 1": {"invalid amount '236,19'"},
+					"entry 3": {"no booking date"},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			xs, e := ParseCAMT(strings.NewReader(tc.document), nil)
+			if !dutil.ErrorEqual(tc.e, e) || !errors.Is(e, ErrValidation) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if len(xs) != tc.n {
+				t.Errorf("expected %d statements got %d", tc.n, len(xs))
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package bankserv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/dottics/dutil"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CDVRule is a check-digit verification (CDV) rule of the account numbers of
+// the branches in a range of South African branch codes. The account number
+// is padded with leading zeros to 11 digits, every digit is multiplied by its
+// weight in the Weighting and the FudgeFactor is added to the sum of the
+// products. The account number is valid if the sum is divisible by the
+// Modulus.
+type CDVRule struct {
+	Bank string
+	// From and To is the inclusive range of the branch codes of the rule.
+	From, To int
+	// MinLength and MaxLength is the range of the number of digits of an
+	// account number.
+	MinLength, MaxLength int
+	// Weighting is the weight of each of the 11 digits. If the Weighting is
+	// empty only the length of an account number is verified.
+	Weighting   string
+	FudgeFactor int
+	Modulus     int
+}
+
+// CDVTable is the table of the check-digit verification rules of the banks.
+// A range of branch codes may have more than one rule, such as the rules of
+// the exceptions of a bank, and an account number is valid if it is valid by
+// any of the rules of the range of its branch code.
+type CDVTable []CDVRule
+
+// AccountNumberLengths is the table used by CheckAccountNumberLength. It has
+// the ranges of the universal branch codes of the banks and the lengths of
+// their account numbers only, it does not have any weightings and therefore
+// does not verify check digits. A table with the weightings, fudge factors,
+// moduli and exceptions of the clearing house's account verification
+// specification is loaded with ParseCDVTable.
+var AccountNumberLengths = CDVTable{
+	{Bank: "Standard Bank", From: 0, To: 99999, MinLength: 9, MaxLength: 11},
+	{Bank: "Nedbank", From: 100000, To: 199999, MinLength: 10, MaxLength: 10},
+	{Bank: "FNB", From: 200000, To: 299999, MinLength: 11, MaxLength: 11},
+	{Bank: "ABSA", From: 300000, To: 349999, MinLength: 9, MaxLength: 11},
+	{Bank: "African Bank", From: 430000, To: 430999, MinLength: 11, MaxLength: 11},
+	{Bank: "Bidvest Bank", From: 462000, To: 462999, MinLength: 10, MaxLength: 11},
+	{Bank: "Capitec", From: 470000, To: 470999, MinLength: 10, MaxLength: 10},
+	{Bank: "Investec", From: 580000, To: 580999, MinLength: 10, MaxLength: 11},
+	{Bank: "ABSA", From: 630000, To: 659999, MinLength: 9, MaxLength: 11},
+	{Bank: "TymeBank", From: 678000, To: 678999, MinLength: 11, MaxLength: 11},
+	{Bank: "Discovery Bank", From: 679000, To: 679999, MinLength: 11, MaxLength: 11},
+}
+
+// cdvColumns are the columns of a CDV table read by ParseCDVTable.
+var cdvColumns = []string{"bank", "from", "to", "min_length", "max_length", "weighting", "fudge_factor", "modulus"}
+
+// ParseCDVTable reads a CDV table from a CSV file with a header row of the
+// columns bank, from, to, min_length, max_length, weighting, fudge_factor and
+// modulus, in any order. A weighting is empty or 11 digits, an empty
+// fudge_factor or modulus is 0. For example
+//
+//	bank,from,to,min_length,max_length,weighting,fudge_factor,modulus
+//	FNB,200000,299999,11,11,<weighting>,0,11
+//
+// Rows which are not valid are reported by line with an error of the kind
+// ErrValidation.
+func ParseCDVTable(r io.Reader) (CDVTable, dutil.Error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return CDVTable{}, csvReadError(err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	v := validation{}
+	for _, name := range cdvColumns {
+		if _, ok := cols[name]; !ok {
+			v.add("header", fmt.Sprintf("column '%s' not found", name))
+		}
+	}
+	if e := v.err(); e != nil {
+		return CDVTable{}, e
+	}
+
+	t := CDVTable{}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				v.add(fmt.Sprintf("line %d", pe.Line), pe.Err.Error())
+				continue
+			}
+			return CDVTable{}, csvReadError(err)
+		}
+		line, _ := cr.FieldPos(0)
+		if blank(row) {
+			continue
+		}
+		rule, reasons := cdvRuleOf(row, cols)
+		if len(reasons) > 0 {
+			v.add(fmt.Sprintf("line %d", line), reasons...)
+			continue
+		}
+		t = append(t, rule)
+	}
+	return t, v.err()
+}
+
+// cdvRuleOf returns the rule of a row of a CDV table, or the reasons the row
+// is not valid.
+func cdvRuleOf(row []string, cols map[string]int) (CDVRule, []string) {
+	reasons := []string{}
+	field := func(name string) string {
+		i := cols[name]
+		if i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	number := func(name string, optional bool) int {
+		s := field(name)
+		if s == "" && optional {
+			return 0
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			reasons = append(reasons, fmt.Sprintf("invalid %s '%s'", name, s))
+		}
+		return n
+	}
+
+	r := CDVRule{
+		Bank:        field("bank"),
+		From:        number("from", false),
+		To:          number("to", false),
+		MinLength:   number("min_length", false),
+		MaxLength:   number("max_length", false),
+		Weighting:   field("weighting"),
+		FudgeFactor: number("fudge_factor", true),
+		Modulus:     number("modulus", true),
+	}
+	if r.Bank == "" {
+		reasons = append(reasons, "bank is required")
+	}
+	if r.From > r.To {
+		reasons = append(reasons, fmt.Sprintf("from %d is after to %d", r.From, r.To))
+	}
+	if r.MinLength < 1 || r.MinLength > r.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("invalid length range %d to %d", r.MinLength, r.MaxLength))
+	}
+	if r.Weighting != "" {
+		if len(r.Weighting) != 11 || strings.Trim(r.Weighting, "0123456789") != "" {
+			reasons = append(reasons, fmt.Sprintf("weighting '%s' must be 11 digits", r.Weighting))
+		}
+		if r.Modulus < 1 {
+			reasons = append(reasons, "modulus is required with a weighting")
+		}
+		if r.MaxLength > 11 {
+			reasons = append(reasons, "max_length must be at most 11 with a weighting")
+		}
+	}
+	return r, reasons
+}
+
+// CheckAccountNumberLength checks that a South African account number only
+// has digits and the number of digits of an account number of the bank of
+// the branch code, with the AccountNumberLengths, see CDVTable.Verify. It
+// does not verify the check digit of the account number, a mistyped digit is
+// not detected. Use CDVTable.Verify with a table of the clearing house's
+// specification, see ParseCDVTable, to verify the check digit.
+func CheckAccountNumberLength(accountNumber, branchCode string) dutil.Error {
+	return AccountNumberLengths.Verify(accountNumber, branchCode)
+}
+
+// Verify verifies a South African account number with the check-digit
+// verification (CDV) rules of the bank of the branch code, for example the
+// Bank.BranchCode of the bank of the account. Spaces and dashes in the
+// account number are ignored.
+//
+// If the account number is valid nil is returned, otherwise an error of the
+// kind ErrValidation with the reason the account number is invalid is
+// returned. The reason is keyed by "account_number", or by "branch_code" if
+// the branch code is unknown.
+//
+// Verify only checks that an account number is able to exist, not that the
+// account exists.
+func (t CDVTable) Verify(accountNumber, branchCode string) dutil.Error {
+	v := validation{}
+	code, err := strconv.Atoi(normaliseBranchCode(branchCode))
+	if err != nil || code < 0 {
+		v.add("branch_code", fmt.Sprintf("invalid branch code '%s'", branchCode))
+		return v.err()
+	}
+	xr := t.rules(code)
+	if len(xr) == 0 {
+		v.add("branch_code", fmt.Sprintf("no rule for branch code '%s'", branchCode))
+		return v.err()
+	}
+
+	n := strings.NewReplacer(" ", "", "-", "").Replace(accountNumber)
+	if n == "" {
+		v.add("account_number", "required field")
+		return v.err()
+	}
+	for _, c := range n {
+		if c < '0' || c > '9' {
+			v.add("account_number", "must only contain digits")
+			return v.err()
+		}
+	}
+	bank := ""
+	for _, r := range xr {
+		if len(n) < r.MinLength || len(n) > r.MaxLength {
+			continue
+		}
+		if r.Weighting == "" || r.verify(n) {
+			return nil
+		}
+		if bank == "" {
+			bank = r.Bank
+		}
+	}
+	if bank == "" {
+		r := xr[0]
+		if r.MinLength == r.MaxLength {
+			v.add("account_number", fmt.Sprintf("must be %d digits for %s", r.MinLength, r.Bank))
+		} else {
+			v.add("account_number", fmt.Sprintf("must be %d to %d digits for %s", r.MinLength, r.MaxLength, r.Bank))
+		}
+		return v.err()
+	}
+	v.add("account_number", fmt.Sprintf("check digit does not match for %s", bank))
+	return v.err()
+}
+
+// rules returns the rules of the range of the branch code in the order of
+// the table.
+func (t CDVTable) rules(branchCode int) []CDVRule {
+	xr := []CDVRule{}
+	for _, r := range t {
+		if branchCode >= r.From && branchCode <= r.To {
+			xr = append(xr, r)
+		}
+	}
+	return xr
+}
+
+// verify reports whether the weighted sum of the digits of the account number
+// is divisible by the modulus of the rule. The account number must only
+// contain digits.
+func (r CDVRule) verify(accountNumber string) bool {
+	if len(accountNumber) > 11 || len(r.Weighting) != 11 || r.Modulus < 1 {
+		return false
+	}
+	n := strings.Repeat("0", 11-len(accountNumber)) + accountNumber
+	sum := r.FudgeFactor
+	for i := 0; i < 11; i++ {
+		sum += int(n[i]-'0') * int(r.Weighting[i]-'0')
+	}
+	return sum%r.Modulus == 0
+}
@@ -0,0 +1,230 @@
+package bankserv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"strings"
+	"testing"
+)
+
+func TestCheckAccountNumberLength(t *testing.T) {
+	tt := []struct {
+		name          string
+		accountNumber string
+		branchCode    string
+		e             dutil.Error
+	}{
+		{
+			name:          "FNB",
+			accountNumber: "62001238911",
+			branchCode:    "250655",
+			e:             nil,
+		},
+		{
+			name:          "FNB with spaces",
+			accountNumber: "620 0123 8911",
+			branchCode:    "250655",
+			e:             nil,
+		},
+		{
+			name:          "FNB mistyped length",
+			accountNumber: "6200123891",
+			branchCode:    "250655",
+			e:             dutil.NewErr(400, "account_number", []string{"must be 11 digits for FNB"}),
+		},
+		{
+			name:          "Standard Bank without leading zero",
+			accountNumber: "012345678",
+			branchCode:    "51001",
+			e:             nil,
+		},
+		{
+			name:          "ABSA with dashes",
+			accountNumber: "40-7123-4567",
+			branchCode:    "632005",
+			e:             nil,
+		},
+		{
+			name:          "ABSA too long",
+			accountNumber: "407123456700",
+			branchCode:    "632005",
+			e:             dutil.NewErr(400, "account_number", []string{"must be 9 to 11 digits for ABSA"}),
+		},
+		{
+			name:          "TymeBank length",
+			accountNumber: "5100123456",
+			branchCode:    "678910",
+			e:             dutil.NewErr(400, "account_number", []string{"must be 11 digits for TymeBank"}),
+		},
+		{
+			name:          "letters",
+			accountNumber: "62O01238911",
+			branchCode:    "250655",
+			e:             dutil.NewErr(400, "account_number", []string{"must only contain digits"}),
+		},
+		{
+			name:          "empty account number",
+			accountNumber: " ",
+			branchCode:    "250655",
+			e:             dutil.NewErr(400, "account_number", []string{"required field"}),
+		},
+		{
+			name:          "invalid branch code",
+			accountNumber: "62001238911",
+			branchCode:    "FNB",
+			e:             dutil.NewErr(400, "branch_code", []string{"invalid branch code 'FNB'"}),
+		},
+		{
+			name:          "unknown branch code",
+			accountNumber: "62001238911",
+			branchCode:    "999999",
+			e:             dutil.NewErr(400, "branch_code", []string{"no rule for branch code '999999'"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := CheckAccountNumberLength(tc.accountNumber, tc.branchCode)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && !errors.Is(e, ErrValidation) {
+				t.Errorf("expected a validation error got %v", e)
+			}
+		})
+	}
+}
+
+// The weightings of the tables of these tests only exercise the arithmetic of
+// a rule, they are not the weightings of the clearing house's specification.
+func TestCDVTable_Verify(t *testing.T) {
+	weighted := "bank,from,to,min_length,max_length,weighting,fudge_factor,modulus\n" +
+		"FNB,200000,299999,11,11,17329874321,0,11\n"
+	exception := weighted + "FNB,250000,250999,11,11,11111111111,,11\n"
+	fudged := "bank,from,to,min_length,max_length,weighting,fudge_factor,modulus\n" +
+		"FNB,200000,299999,11,11,17329874321,4,11\n"
+
+	tt := []struct {
+		name          string
+		table         string
+		accountNumber string
+		branchCode    string
+		e             dutil.Error
+	}{
+		{
+			name:          "weighted",
+			table:         weighted,
+			accountNumber: "62001238915",
+			branchCode:    "250655",
+			e:             nil,
+		},
+		{
+			name:          "check digit",
+			table:         weighted,
+			accountNumber: "62001238911",
+			branchCode:    "250655",
+			e:             dutil.NewErr(400, "account_number", []string{"check digit does not match for FNB"}),
+		},
+		{
+			name:          "fudge factor",
+			table:         fudged,
+			accountNumber: "62001238911",
+			branchCode:    "250655",
+			e:             nil,
+		},
+		{
+			name:          "valid by an exception",
+			table:         exception,
+			accountNumber: "62001238911",
+			branchCode:    "250655",
+			e:             nil,
+		},
+		{
+			name:          "outside the range of an exception",
+			table:         exception,
+			accountNumber: "62001238911",
+			branchCode:    "210554",
+			e:             dutil.NewErr(400, "account_number", []string{"check digit does not match for FNB"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			table, e := ParseCDVTable(strings.NewReader(tc.table))
+			if e != nil {
+				t.Fatalf("unexpected error %v", e)
+			}
+			e = table.Verify(tc.accountNumber, tc.branchCode)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+		})
+	}
+}
+
+func TestParseCDVTable(t *testing.T) {
+	tt := []struct {
+		name  string
+		table string
+		rules CDVTable
+		e     dutil.Error
+	}{
+		{
+			name: "columns in any order",
+			table: "Modulus,Weighting,Bank,From,To,Min_Length,Max_Length,Fudge_Factor\n" +
+				"11,17329874321,FNB,200000,299999,11,11,0\n" +
+				",,TymeBank,678000,678999,11,11,\n",
+			rules: CDVTable{
+				{Bank: "FNB", From: 200000, To: 299999, MinLength: 11, MaxLength: 11, Weighting: "17329874321", Modulus: 11},
+				{Bank: "TymeBank", From: 678000, To: 678999, MinLength: 11, MaxLength: 11},
+			},
+		},
+		{
+			name:  "missing column",
+			table: "bank,from,to,min_length,max_length,weighting,modulus\n",
+			rules: CDVTable{},
+			e:     dutil.NewErr(400, "header", []string{"column 'fudge_factor' not found"}),
+		},
+		{
+			name: "invalid rows",
+			table: "bank,from,to,min_length,max_length,weighting,fudge_factor,modulus\n" +
+				"FNB,299999,200000,11,11,1732987432,0,\n" +
+				",200000,299999,eleven,11,,0,11\n",
+			rules: CDVTable{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: dutil.Errors{
+					"line 2": {
+						"from 299999 is after to 200000",
+						"weighting '1732987432' must be 11 digits",
+						"modulus is required with a weighting",
+					},
+					"line 3": {
+						"invalid min_length 'eleven'",
+						"bank is required",
+						"invalid length range 0 to 11",
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			rules, e := ParseCDVTable(strings.NewReader(tc.table))
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && !errors.Is(e, ErrValidation) {
+				t.Errorf("expected a validation error got %v", e)
+			}
+			if fmt.Sprint(rules) != fmt.Sprint(tc.rules) {
+				t.Errorf("expected rules %v got %v", tc.rules, rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package bankserv
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a bank statement.
+type Encoding int
+
+const (
+	// UTF8 is the UTF-8 encoding, a byte order mark is ignored.
+	UTF8 Encoding = iota
+	// Latin1 is the ISO 8859-1 encoding.
+	Latin1
+	// Windows1252 is the Windows-1252 encoding, which most spreadsheet
+	// programs on Windows use to export CSV files.
+	Windows1252
+)
+
+// windows1252 holds the characters of the bytes 0x80 to 0x9F of the
+// Windows-1252 encoding, which are control characters in ISO 8859-1. The
+// bytes which are not used by Windows-1252 are mapped to the same code point
+// as in ISO 8859-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// decode decodes the bytes in the encoding to a UTF-8 string. Bytes which
+// are not valid UTF-8 in a UTF-8 statement are replaced by the replacement
+// character.
+func (enc Encoding) decode(xb []byte) string {
+	switch enc {
+	case Latin1, Windows1252:
+		var b strings.Builder
+		b.Grow(len(xb))
+		for _, c := range xb {
+			if enc == Windows1252 && c >= 0x80 && c <= 0x9f {
+				b.WriteRune(windows1252[c-0x80])
+				continue
+			}
+			b.WriteRune(rune(c))
+		}
+		return b.String()
+	}
+	xb = bytes.TrimPrefix(xb, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(xb) {
+		return string(xb)
+	}
+	return strings.ToValidUTF8(string(xb), string(utf8.RuneError))
+}
@@ -0,0 +1,56 @@
+package bankserv
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEncoding_decode(t *testing.T) {
+	tt := []struct {
+		name     string
+		encoding Encoding
+		xb       []byte
+		s        string
+	}{
+		{
+			name:     "utf-8",
+			encoding: UTF8,
+			xb:       []byte("Caf\xc3\xa9 \xe2\x82\xac5"),
+			s:        "Café €5",
+		},
+		{
+			name:     "utf-8 with byte order mark",
+			encoding: UTF8,
+			xb:       []byte("\xef\xbb\xbfDate"),
+			s:        "Date",
+		},
+		{
+			name:     "invalid utf-8",
+			encoding: UTF8,
+			xb:       []byte("Caf\xe9"),
+			s:        "Caf�",
+		},
+		{
+			name:     "latin-1",
+			encoding: Latin1,
+			xb:       []byte("Caf\xe9 \x80"),
+			s:        "Café \u0080",
+		},
+		{
+			name:     "windows-1252",
+			encoding: Windows1252,
+			xb:       []byte("Caf\xe9 \x80 \x93quoted\x94 \x81"),
+			s:        "Café € “quoted” \u0081",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := tc.encoding.decode(tc.xb)
+			if s != tc.s {
+				t.Errorf("expected %q got %q", tc.s, s)
+			}
+		})
+	}
+}
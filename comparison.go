@@ -31,10 +31,10 @@ func EqualItem(a, b Item) bool {
 	if a.Description != b.Description {
 		return false
 	}
-	if a.Amount != b.Amount {
+	if !a.Amount.Equal(b.Amount) {
 		return false
 	}
-	if a.Discount != b.Discount {
+	if !a.Discount.Equal(b.Discount) {
 		return false
 	}
 	if a.SKU != b.SKU {
@@ -80,9 +80,21 @@ func EqualTransaction(a, b Transaction) bool {
 	if a.Date != b.Date {
 		return false
 	}
+	if (a.ValueDate == nil) != (b.ValueDate == nil) {
+		return false
+	}
+	if a.ValueDate != nil && *a.ValueDate != *b.ValueDate {
+		return false
+	}
 	if a.Description != b.Description {
 		return false
 	}
+	if a.CounterpartUUID != b.CounterpartUUID {
+		return false
+	}
+	if a.ExternalID != b.ExternalID {
+		return false
+	}
 	if a.Active != b.Active {
 		return false
 	}
@@ -110,13 +110,13 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          236.189,
+				Amount:          Money{MinorUnits: 23619},
 			},
 			b: Item{
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
+				Amount:          Money{MinorUnits: 3760},
 			},
 			o: false,
 		},
@@ -126,15 +126,15 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        236.189,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 23619},
 			},
 			b: Item{
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        192.12,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 19212},
 			},
 			o: false,
 		},
@@ -144,17 +144,17 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.95,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3950},
 			},
 			b: Item{
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 			},
 			o: false,
 		},
@@ -164,18 +164,18 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.95,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3950},
 				Active:          true,
 			},
 			b: Item{
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          false,
 			},
 			o: false,
@@ -186,9 +186,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.95,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3950},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 			},
@@ -196,9 +196,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:54:11.000Z"),
 			},
@@ -210,9 +210,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.95,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3950},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -221,9 +221,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:55:01.000Z"),
@@ -236,9 +236,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -248,9 +248,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -264,9 +264,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -278,9 +278,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -294,9 +294,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -308,9 +308,9 @@ func TestEqualItem(t *testing.T) {
 				UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 				TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 				Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-				Amount:          37.6,
-				Discount:        3.45,
-				SKU:             3.15,
+				Amount:          Money{MinorUnits: 3760},
+				Discount:        Money{MinorUnits: 345},
+				SKU:             Quantity{Thousandths: 3150},
 				Active:          true,
 				CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 				UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -347,9 +347,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -361,9 +361,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("afa3a1f9-1822-48b4-874b-1c5ff974af30"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -377,9 +377,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -397,9 +397,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -411,9 +411,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("afa3a1f9-1822-48b4-874b-1c5ff974af30"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -427,9 +427,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -441,9 +441,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("2fa81848-229a-464a-8881-f04046d4f430"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "GOOGLE *GOOGLE STORAGEG.CO/HELPPAY#GB",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -461,9 +461,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -475,9 +475,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("2fa81848-229a-464a-8881-f04046d4f430"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "GOOGLE *GOOGLE STORAGEG.CO/HELPPAY#GB",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -491,9 +491,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -505,9 +505,9 @@ func TestEqualItems(t *testing.T) {
 					UUID:            uuid.MustParse("2fa81848-229a-464a-8881-f04046d4f430"),
 					TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
 					Description:     "GOOGLE *GOOGLE STORAGEG.CO/HELPPAY#GB",
-					Amount:          37.6,
-					Discount:        3.45,
-					SKU:             3.15,
+					Amount:          Money{MinorUnits: 3760},
+					Discount:        Money{MinorUnits: 345},
+					SKU:             Quantity{Thousandths: 3150},
 					Active:          true,
 					CreateDate:      timeMustParse("2022-06-18T13:53:57.000Z"),
 					UpdateDate:      timeMustParse("2022-06-18T13:54:45.000Z"),
@@ -596,6 +596,42 @@ func TestEqualTransaction(t *testing.T) {
 			},
 			o: false,
 		},
+		{
+			name: "different Counterpart UUID",
+			a: Transaction{
+				UUID:            uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+				CounterpartUUID: uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb"),
+			},
+			b: Transaction{
+				UUID: uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+			},
+			o: false,
+		},
+		{
+			name: "different Value Date",
+			a: Transaction{
+				UUID:      uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+				Date:      timeMustParse("2022-06-18T00:00:00.000Z"),
+				ValueDate: timeMustParsePtr("2022-06-17T00:00:00.000Z"),
+			},
+			b: Transaction{
+				UUID: uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+				Date: timeMustParse("2022-06-18T00:00:00.000Z"),
+			},
+			o: false,
+		},
+		{
+			name: "different External ID",
+			a: Transaction{
+				UUID:       uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+				ExternalID: "20220618-001",
+			},
+			b: Transaction{
+				UUID:       uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+				ExternalID: "20220618-002",
+			},
+			o: false,
+		},
 		{
 			name: "different Active",
 			a: Transaction{
@@ -0,0 +1,370 @@
+package bankserv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVMapping describes the layout of a CSV bank statement, such that
+// ParseCSV is able to read the transactions of the statement.
+//
+// A column of the mapping is the name of the column in the header row, or
+// the number of the column starting at 1, for example "3", if the statement
+// does not have a header row. Names are matched regardless of case and
+// surrounding spaces.
+type CSVMapping struct {
+	// Comma is the field separator, if zero a comma is used.
+	Comma rune
+	// Encoding is the character encoding of the statement.
+	Encoding Encoding
+	// SkipRows is the number of rows before the header row, or before the
+	// first transaction if there is no header row, such as the account
+	// details at the top of a statement.
+	SkipRows int
+	// Header reports whether the statement has a header row with the names of
+	// the columns. If FindHeader is set the header row is the first row, after
+	// SkipRows, with the names of every column of the mapping, such that a
+	// statement with a varying number of rows above the header row is read.
+	Header     bool
+	FindHeader bool
+	// IgnoreUndated reports whether the rows of which the date is not a date,
+	// such as the balances and totals at the bottom of a statement, are
+	// ignored instead of reported as errors.
+	IgnoreUndated bool
+
+	// Date is the column of the date of the transaction and DateLayout is the
+	// layout of the date, see time.Parse. Dates are parsed in UTC.
+	Date       string
+	DateLayout string
+	// ValueDate is the column of the value date of the transaction, which has
+	// the same layout as the date.
+	ValueDate string
+	// Description is the column of the description of the transaction.
+	Description string
+	// Amount is the column of the signed amount of the transaction. If the
+	// statement has separate columns for debits and credits use Debit and
+	// Credit instead.
+	Amount string
+	// Debit is the column of the amount debited, which is negated regardless
+	// of its sign, and Credit is the column of the amount credited.
+	Debit  string
+	Credit string
+	// Fee is the column of a fee charged with the transaction, which is
+	// negated regardless of its sign. A fee which is not zero is a separate
+	// item of the transaction with the description "Fee".
+	Fee string
+	// DecimalSeparator is the decimal separator of the amounts, if zero a
+	// period is used. ThousandsSeparator is the separator of the groups of
+	// thousands of the amounts, if any.
+	DecimalSeparator   rune
+	ThousandsSeparator rune
+	// Currency is the currency of the amounts, if empty the DefaultCurrency
+	// is used.
+	Currency string
+}
+
+// csvColumns are the indexes of the columns of a mapping in the rows of a
+// statement, an index of -1 is a column which is not mapped.
+type csvColumns struct {
+	date, valueDate, description, amount, debit, credit, fee int
+}
+
+// ParseCSV reads the transactions of the bank account from a CSV bank
+// statement with the layout of the mapping. Every transaction has a single
+// item with the description and amount of the transaction.
+//
+// The rows which are not able to be parsed are skipped and reported in an
+// error of the kind ErrValidation, which is keyed by the line of the row, for
+// example "line 7", such that the transactions of the other rows are still
+// returned. Rows without any values are ignored.
+//
+// The amounts may be signed, "-236.19", or be in parentheses, "(236.19)",
+// have a trailing minus, "236.19-", or have the suffix Dr or Cr, "236.19 Dr",
+// of which the first three and Dr are negative.
+func ParseCSV(r io.Reader, accountUUID uuid.UUID, m CSVMapping) (Transactions, dutil.Error) {
+	xb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Transactions{}, dutil.NewErr(500, "read", []string{err.Error()})
+	}
+	cr := csv.NewReader(strings.NewReader(m.Encoding.decode(xb)))
+	if m.Comma != 0 {
+		cr.Comma = m.Comma
+	}
+	// statements often have rows with a different number of fields, such as
+	// the account details and totals
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	for i := 0; i < m.SkipRows; i++ {
+		if _, err := cr.Read(); err != nil {
+			return Transactions{}, csvReadError(err)
+		}
+	}
+	var header []string
+	switch {
+	case m.Header && m.FindHeader:
+		for header == nil {
+			row, err := cr.Read()
+			if err == io.EOF {
+				// the columns of the mapping are reported as unknown columns
+				break
+			}
+			if err != nil {
+				return Transactions{}, csvReadError(err)
+			}
+			if _, e := m.columns(row); e == nil {
+				header = row
+			}
+		}
+	case m.Header:
+		row, err := cr.Read()
+		if err != nil {
+			return Transactions{}, csvReadError(err)
+		}
+		header = row
+	}
+	cols, e := m.columns(header)
+	if e != nil {
+		return Transactions{}, e
+	}
+
+	xt := Transactions{}
+	v := validation{}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				v.add(fmt.Sprintf("line %d", pe.Line), pe.Err.Error())
+				continue
+			}
+			return Transactions{}, csvReadError(err)
+		}
+		line, _ := cr.FieldPos(0)
+		if blank(row) {
+			continue
+		}
+		t, reasons := m.transaction(row, cols)
+		if len(reasons) > 0 && m.IgnoreUndated && t.Date.IsZero() {
+			continue
+		}
+		if len(reasons) > 0 {
+			v.add(fmt.Sprintf("line %d", line), reasons...)
+			continue
+		}
+		t.AccountUUID = accountUUID
+		xt = append(xt, t)
+	}
+	return xt, v.err()
+}
+
+// csvReadError returns the error of a statement which is not able to be
+// read at all.
+func csvReadError(err error) dutil.Error {
+	if err == io.EOF {
+		return newError(dutil.NewErr(400, "csv", []string{"unexpected end of the statement"}))
+	}
+	return newError(dutil.NewErr(400, "csv", []string{err.Error()}))
+}
+
+// columns returns the indexes of the columns of the mapping in the header
+// row passed to the function. An error of the kind ErrValidation keyed by the
+// field of the mapping is returned if a column does not exist or a required
+// column is not mapped.
+func (m CSVMapping) columns(header []string) (csvColumns, dutil.Error) {
+	v := validation{}
+	index := func(field, column string) int {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return -1
+		}
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				return i
+			}
+		}
+		if n, err := strconv.Atoi(column); err == nil && n > 0 {
+			return n - 1
+		}
+		v.add(field, fmt.Sprintf("unknown column '%s'", column))
+		return -1
+	}
+	cols := csvColumns{
+		date:        index("date", m.Date),
+		valueDate:   index("value_date", m.ValueDate),
+		description: index("description", m.Description),
+		amount:      index("amount", m.Amount),
+		debit:       index("debit", m.Debit),
+		credit:      index("credit", m.Credit),
+		fee:         index("fee", m.Fee),
+	}
+	if m.Date == "" {
+		v.add("date", "required field")
+	}
+	if m.DateLayout == "" {
+		v.add("date_layout", "required field")
+	}
+	if m.Amount == "" && m.Debit == "" && m.Credit == "" {
+		v.add("amount", "required field if debit and credit are not set")
+	}
+	if m.Amount != "" && (m.Debit != "" || m.Credit != "") {
+		v.add("amount", "only one of amount and debit and credit may be set")
+	}
+	return cols, v.err()
+}
+
+// transaction returns the transaction of a row of the statement, or the
+// reasons the row is not able to be parsed. The transaction of a row which is
+// not able to be parsed only has the date, if the date is valid.
+func (m CSVMapping) transaction(row []string, cols csvColumns) (Transaction, []string) {
+	var reasons []string
+	value := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	date, err := time.Parse(m.DateLayout, value(cols.date))
+	if err != nil {
+		reasons = append(reasons, fmt.Sprintf("date: invalid date '%s' for layout '%s'", value(cols.date), m.DateLayout))
+	}
+	var valueDate *time.Time
+	if v := value(cols.valueDate); v != "" {
+		t, err := time.Parse(m.DateLayout, v)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("value_date: invalid date '%s' for layout '%s'", v, m.DateLayout))
+		}
+		valueDate = &t
+	}
+
+	var amount Money
+	if cols.amount >= 0 {
+		a, reason := m.money(value(cols.amount))
+		if reason != "" {
+			reasons = append(reasons, "amount: "+reason)
+		}
+		amount = a
+	} else {
+		debit, credit := value(cols.debit), value(cols.credit)
+		if debit == "" && credit == "" {
+			reasons = append(reasons, "amount: no debit or credit")
+		}
+		amount = NewMoney(0, m.Currency)
+		if credit != "" {
+			a, reason := m.money(credit)
+			if reason != "" {
+				reasons = append(reasons, "credit: "+reason)
+			}
+			amount = a
+		}
+		if debit != "" {
+			a, reason := m.money(debit)
+			if reason != "" {
+				reasons = append(reasons, "debit: "+reason)
+			}
+			if a.Sign() > 0 {
+				a = a.Neg()
+			}
+			amount.MinorUnits += a.MinorUnits
+		}
+	}
+	var fee Money
+	if v := value(cols.fee); v != "" {
+		a, reason := m.money(v)
+		if reason != "" {
+			reasons = append(reasons, "fee: "+reason)
+		}
+		if a.Sign() > 0 {
+			a = a.Neg()
+		}
+		fee = a
+	}
+	if len(reasons) > 0 {
+		return Transaction{Date: date}, reasons
+	}
+
+	description := value(cols.description)
+	items := Items{
+		{
+			Description: description,
+			SKU:         Units(1),
+			Amount:      amount,
+			Active:      true,
+		},
+	}
+	if !fee.IsZero() {
+		items = append(items, Item{
+			Description: "Fee",
+			SKU:         Units(1),
+			Amount:      fee,
+			Active:      true,
+		})
+	}
+	return Transaction{
+		Date:        date,
+		ValueDate:   valueDate,
+		Description: description,
+		Items:       items,
+		Active:      true,
+	}, nil
+}
+
+// money parses an amount of the statement with the separators of the
+// mapping, or returns the reason the amount is invalid. An amount in
+// parentheses, "(12.50)", with a trailing minus, "12.50-", or with the suffix
+// Dr, "12.50 Dr", is negative.
+func (m CSVMapping) money(s string) (Money, string) {
+	v := strings.TrimSpace(s)
+	negative := false
+	switch upper := strings.ToUpper(v); {
+	case strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")"):
+		negative = true
+		v = strings.TrimSpace(v[1 : len(v)-1])
+	case strings.HasSuffix(upper, "DR"):
+		negative = true
+		v = strings.TrimSpace(v[:len(v)-2])
+	case strings.HasSuffix(upper, "CR"):
+		v = strings.TrimSpace(v[:len(v)-2])
+	case len(v) > 1 && strings.HasSuffix(v, "-"):
+		negative = true
+		v = strings.TrimSpace(v[:len(v)-1])
+	}
+	if m.ThousandsSeparator != 0 {
+		v = strings.Replace(v, string(m.ThousandsSeparator), "", -1)
+	}
+	if m.DecimalSeparator != 0 && m.DecimalSeparator != '.' {
+		v = strings.Replace(v, string(m.DecimalSeparator), ".", -1)
+	}
+	// spaces are a thousands separator in many locales
+	v = strings.Replace(v, " ", "", -1)
+	v = strings.Replace(v, "\u00a0", "", -1)
+	a, e := ParseMoney(v, m.Currency)
+	if e != nil {
+		return Money{}, fmt.Sprintf("invalid amount '%s'", s)
+	}
+	if negative {
+		a = a.Neg()
+	}
+	return a, ""
+}
+
+// blank reports whether every field of the row is empty.
+func blank(row []string) bool {
+	for _, f := range row {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package bankserv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// CSVPreset is the mapping of the CSV statements which a bank exports, such
+// that the statements of the bank are read without a mapping of their own.
+type CSVPreset struct {
+	// Name is the name of the preset, such as "fnb", and Bank is the name of
+	// the bank.
+	Name string
+	Bank string
+	// Mapping is the layout of the statements of the bank.
+	Mapping CSVMapping
+	// Signature is the values of a row which identify a statement of the
+	// bank, such as the names of the columns of the header row. Values are
+	// matched regardless of case and surrounding spaces.
+	Signature []string
+}
+
+// csvDetectRows is the number of rows at the top of a statement which are
+// searched for the signature of a preset.
+const csvDetectRows = 50
+
+// CSVPresets are the presets of the CSV statements of the South African
+// banks, which are tried in order by DetectCSVPreset.
+var CSVPresets = []CSVPreset{
+	{
+		Name: "fnb",
+		Bank: "First National Bank",
+		Mapping: CSVMapping{
+			Header:             true,
+			FindHeader:         true,
+			IgnoreUndated:      true,
+			Date:               "Date",
+			DateLayout:         "2006/01/02",
+			Description:        "Description",
+			Amount:             "Amount",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"Date", "Amount", "Balance", "Description"},
+	},
+	{
+		Name: "absa",
+		Bank: "Absa",
+		Mapping: CSVMapping{
+			Header:             true,
+			FindHeader:         true,
+			IgnoreUndated:      true,
+			Date:               "Transaction Date",
+			DateLayout:         "20060102",
+			Description:        "Description",
+			Amount:             "Amount",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"Transaction Date", "Description", "Amount", "Balance"},
+	},
+	{
+		// the transactions are the HIST rows of a statement without a header
+		// row, the other rows are the account and the balances
+		Name: "standardbank",
+		Bank: "Standard Bank",
+		Mapping: CSVMapping{
+			IgnoreUndated:      true,
+			Date:               "2",
+			DateLayout:         "20060102",
+			Amount:             "4",
+			Description:        "5",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"HIST"},
+	},
+	{
+		Name: "nedbank",
+		Bank: "Nedbank",
+		Mapping: CSVMapping{
+			Header:             true,
+			FindHeader:         true,
+			IgnoreUndated:      true,
+			Date:               "Transaction date",
+			DateLayout:         "02Jan2006",
+			Description:        "Transaction description",
+			Amount:             "Amount",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"Transaction date", "Transaction description", "Amount", "Available balance"},
+	},
+	{
+		Name: "capitec",
+		Bank: "Capitec",
+		Mapping: CSVMapping{
+			Header:             true,
+			FindHeader:         true,
+			IgnoreUndated:      true,
+			Date:               "Posting Date",
+			ValueDate:          "Transaction Date",
+			DateLayout:         "2006-01-02",
+			Description:        "Description",
+			Debit:              "Money Out",
+			Credit:             "Money In",
+			Fee:                "Fee",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"Posting Date", "Transaction Date", "Description", "Money In", "Money Out", "Fee"},
+	},
+	{
+		Name: "discovery",
+		Bank: "Discovery Bank",
+		Mapping: CSVMapping{
+			Header:             true,
+			FindHeader:         true,
+			IgnoreUndated:      true,
+			Date:               "Transaction Date",
+			ValueDate:          "Value Date",
+			DateLayout:         "2006-01-02",
+			Description:        "Description",
+			Amount:             "Amount",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"Transaction Date", "Value Date", "Description", "Amount", "Balance"},
+	},
+	{
+		Name: "tymebank",
+		Bank: "TymeBank",
+		Mapping: CSVMapping{
+			Header:             true,
+			FindHeader:         true,
+			IgnoreUndated:      true,
+			Date:               "Date",
+			DateLayout:         "02 Jan 2006",
+			Description:        "Description",
+			Debit:              "Money Out",
+			Credit:             "Money In",
+			Fee:                "Fees",
+			ThousandsSeparator: ',',
+			Currency:           "ZAR",
+		},
+		Signature: []string{"Date", "Description", "Money In", "Money Out", "Fees", "Balance"},
+	},
+}
+
+// GetCSVPreset returns the preset with the name, regardless of case, or false
+// if there is no preset with the name.
+func GetCSVPreset(name string) (CSVPreset, bool) {
+	for _, p := range CSVPresets {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, true
+		}
+	}
+	return CSVPreset{}, false
+}
+
+// DetectCSVPreset returns the preset of the bank which exported the CSV
+// statement, or false if the statement is not of any of the CSVPresets. The
+// preset of which the signature is in one of the first rows of the statement
+// is returned. If the signature of more than one preset is in the
+// statement, the preset with the longest signature is returned, such that a
+// statement of which the header row has every column of another bank and more
+// is not mistaken for the statement of the other bank.
+func DetectCSVPreset(statement []byte) (CSVPreset, bool) {
+	var best CSVPreset
+	found := false
+	for _, p := range CSVPresets {
+		if found && len(p.Signature) <= len(best.Signature) {
+			continue
+		}
+		if p.detect(statement) {
+			best = p
+			found = true
+		}
+	}
+	return best, found
+}
+
+// detect reports whether the signature of the preset is in one of the first
+// rows of the statement.
+func (p CSVPreset) detect(statement []byte) bool {
+	if len(p.Signature) == 0 {
+		return false
+	}
+	cr := csv.NewReader(strings.NewReader(p.Mapping.Encoding.decode(statement)))
+	if p.Mapping.Comma != 0 {
+		cr.Comma = p.Mapping.Comma
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	for i := 0; i < csvDetectRows; i++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			continue
+		}
+		values := make(map[string]bool, len(row))
+		for _, f := range row {
+			values[strings.ToLower(strings.TrimSpace(f))] = true
+		}
+		match := true
+		for _, s := range p.Signature {
+			if !values[strings.ToLower(s)] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseBankCSV reads the transactions of the bank account from a CSV
+// statement of one of the CSVPresets, which is detected with
+// DetectCSVPreset. The transactions are ready to be created with
+// CreateTransaction or CreateTransactions.
+//
+// A statement which is not of any of the presets returns an error of the kind
+// ErrValidation with the key "preset", otherwise the errors are those of
+// ParseCSV.
+func ParseBankCSV(r io.Reader, accountUUID uuid.UUID) (Transactions, dutil.Error) {
+	xb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Transactions{}, dutil.NewErr(500, "read", []string{err.Error()})
+	}
+	p, ok := DetectCSVPreset(xb)
+	if !ok {
+		return Transactions{}, newError(dutil.NewErr(400, "preset", []string{"the bank of the statement is not known"}))
+	}
+	return ParseCSV(bytes.NewReader(xb), accountUUID, p.Mapping)
+}
@@ -0,0 +1,166 @@
+package bankserv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+)
+
+func TestParseBankCSV(t *testing.T) {
+	accountUUID := uuid.MustParse("032203af-6002-4abc-9982-73c577add8df")
+	superspar := Transaction{
+		Date:        timeMustParse("2022-06-18T00:00:00Z"),
+		Description: "SUPERSPAR JEFFREYS BAY",
+		Items:       Items{{Description: "SUPERSPAR JEFFREYS BAY", SKU: Units(1), Amount: NewMoney(-23619, "ZAR"), Active: true}},
+		Active:      true,
+	}
+	salary := Transaction{
+		Date:        timeMustParse("2022-06-25T00:00:00Z"),
+		Description: "SALARY",
+		Items:       Items{{Description: "SALARY", SKU: Units(1), Amount: NewMoney(1500000, "ZAR"), Active: true}},
+		Active:      true,
+	}
+	// withValueDate returns the transaction with the value date
+	withValueDate := func(t Transaction, date string) Transaction {
+		t.ValueDate = timeMustParsePtr(date)
+		return t
+	}
+	// withFee returns the transaction with a fee item
+	withFee := func(t Transaction, fee int64) Transaction {
+		t.Items = append(Items{}, t.Items...)
+		t.Items = append(t.Items, Item{Description: "Fee", SKU: Units(1), Amount: NewMoney(fee, "ZAR"), Active: true})
+		return t
+	}
+
+	tt := []struct {
+		name      string
+		statement string
+		preset    string
+		expected  Transactions
+	}{
+		{
+			name: "fnb",
+			statement: "ACCOUNT TRANSACTIONS\n" +
+				"2,\"Gold Business Account\",\"62001238911\"\n" +
+				"3,\"Opening balance\",10000.00\n" +
+				"Date,Amount,Balance,Description\n" +
+				"2022/06/18,-236.19,9763.81,\"SUPERSPAR JEFFREYS BAY\"\n" +
+				"2022/06/25,15000.00,24763.81,\"SALARY\"\n" +
+				"\n" +
+				"Closing balance,24763.81\n",
+			preset:   "fnb",
+			expected: Transactions{superspar, salary},
+		},
+		{
+			name: "absa",
+			statement: "Account,4071234567,Cheque\n" +
+				"Transaction Date,Description,Amount,Balance\n" +
+				"20220618,SUPERSPAR JEFFREYS BAY,236.19-,9763.81\n" +
+				"20220625,SALARY,\"15,000.00\",24763.81\n" +
+				"Total,,14763.81,\n",
+			preset:   "absa",
+			expected: Transactions{superspar, salary},
+		},
+		{
+			name: "standard bank",
+			statement: "ACC-NO,012345678,,,,\n" +
+				"OPEN,0,10000.00,,OPENING BALANCE,\n" +
+				"HIST,20220618,,-236.19,SUPERSPAR JEFFREYS BAY,POS PURCHASE,\n" +
+				"HIST,20220625,,15000.00,SALARY,CREDIT TRANSFER,\n" +
+				"CLOSE,0,24763.81,,CLOSING BALANCE,\n",
+			preset:   "standardbank",
+			expected: Transactions{superspar, salary},
+		},
+		{
+			name: "nedbank",
+			statement: "Account number,1234567890\n" +
+				"Statement period,01Jun2022 - 30Jun2022\n" +
+				"Transaction date,Transaction description,Amount,Available balance\n" +
+				"18Jun2022,SUPERSPAR JEFFREYS BAY,236.19Dr,9763.81Cr\n" +
+				"25Jun2022,SALARY,15000.00Cr,24763.81Cr\n",
+			preset:   "nedbank",
+			expected: Transactions{superspar, salary},
+		},
+		{
+			name: "capitec",
+			statement: "Nr,Account,Posting Date,Transaction Date,Description,Original Description,Parent Category,Category,Money In,Money Out,Fee,Balance\n" +
+				"1,1234567890,2022-06-18,2022-06-17,SUPERSPAR JEFFREYS BAY,SUPERSPAR JBAY,Food,Groceries,,-236.19,-0.50,9763.31\n" +
+				"2,1234567890,2022-06-25,2022-06-25,SALARY,SALARY,Income,Salary,15000.00,,,24763.31\n",
+			preset: "capitec",
+			expected: Transactions{
+				withFee(withValueDate(superspar, "2022-06-17T00:00:00Z"), -50),
+				withValueDate(salary, "2022-06-25T00:00:00Z"),
+			},
+		},
+		{
+			name: "discovery",
+			statement: "Transaction Date,Value Date,Description,Amount,Balance\n" +
+				"2022-06-18,2022-06-19,SUPERSPAR JEFFREYS BAY,-236.19,9763.81\n" +
+				"2022-06-25,2022-06-25,SALARY,15000.00,24763.81\n",
+			preset: "discovery",
+			expected: Transactions{
+				withValueDate(superspar, "2022-06-19T00:00:00Z"),
+				withValueDate(salary, "2022-06-25T00:00:00Z"),
+			},
+		},
+		{
+			name: "tymebank",
+			statement: "Account Statement,TymeBank EveryDay Account\n" +
+				"Date,Description,Fees,Money Out,Money In,Balance\n" +
+				"18 Jun 2022,SUPERSPAR JEFFREYS BAY,1.00,236.19,,9762.81\n" +
+				"25 Jun 2022,SALARY,,,15000.00,24762.81\n",
+			preset:   "tymebank",
+			expected: Transactions{withFee(superspar, -100), salary},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			p, ok := DetectCSVPreset([]byte(tc.statement))
+			if !ok || p.Name != tc.preset {
+				t.Errorf("expected preset %s got %s", tc.preset, p.Name)
+			}
+			if gp, ok := GetCSVPreset(strings.ToUpper(tc.preset)); !ok || gp.Name != tc.preset {
+				t.Errorf("expected to get preset %s got %v", tc.preset, gp.Name)
+			}
+
+			xt, e := ParseBankCSV(strings.NewReader(tc.statement), accountUUID)
+			if e != nil {
+				t.Fatalf("unexpected error %v", e)
+			}
+			for j := range tc.expected {
+				tc.expected[j].AccountUUID = accountUUID
+			}
+			if !EqualTransactions(tc.expected, xt) {
+				t.Errorf("expected %v got %v", tc.expected, xt)
+			}
+			for _, txn := range xt {
+				if e := txn.Validate(); e != nil {
+					t.Errorf("expected a valid transaction got %v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBankCSV_unknown(t *testing.T) {
+	statement := "Datum;Omschrijving;Bedrag\n18.06.2022;Café;-12,50\n"
+	if p, ok := DetectCSVPreset([]byte(statement)); ok {
+		t.Errorf("expected no preset got %s", p.Name)
+	}
+	if _, ok := GetCSVPreset("ing"); ok {
+		t.Errorf("expected no preset with the name ing")
+	}
+	xt, e := ParseBankCSV(strings.NewReader(statement), uuid.New())
+	te := dutil.NewErr(400, "preset", []string{"the bank of the statement is not known"})
+	if !dutil.ErrorEqual(te, e) || !errors.Is(e, ErrValidation) {
+		t.Errorf("expected error %v got %v", te, e)
+	}
+	if len(xt) != 0 {
+		t.Errorf("expected no transactions got %d", len(xt))
+	}
+}
@@ -0,0 +1,248 @@
+package bankserv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	accountUUID := uuid.MustParse("032203af-6002-4abc-9982-73c577add8df")
+	signed := CSVMapping{
+		Header:      true,
+		Date:        "Date",
+		DateLayout:  "2006-01-02",
+		Description: "Description",
+		Amount:      "Amount",
+	}
+
+	type row struct {
+		date        string
+		description string
+		amount      int64
+	}
+	tt := []struct {
+		name      string
+		mapping   CSVMapping
+		statement string
+		rows      []row
+		e         dutil.Error
+	}{
+		{
+			name:    "signed amount",
+			mapping: signed,
+			statement: "Date,Description,Amount\n" +
+				"2022-06-18,SUPERSPAR JEFFREYS BAY,-236.19\n" +
+				"2022-06-20,SALARY,15000.00\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "SUPERSPAR JEFFREYS BAY", -23619},
+				{"2022-06-20T00:00:00Z", "SALARY", 1500000},
+			},
+		},
+		{
+			name: "debit and credit columns by number",
+			mapping: CSVMapping{
+				Comma:       ';',
+				SkipRows:    2,
+				Date:        "1",
+				DateLayout:  "02/01/2006",
+				Description: "2",
+				Debit:       "3",
+				Credit:      "4",
+			},
+			statement: "Account;62001238911\n" +
+				"Statement;June 2022\n" +
+				"18/06/2022;SUPERSPAR;236.19;\n" +
+				"20/06/2022;SALARY;;15000.00\n" +
+				"21/06/2022;FEE;-5.00;\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "SUPERSPAR", -23619},
+				{"2022-06-20T00:00:00Z", "SALARY", 1500000},
+				{"2022-06-21T00:00:00Z", "FEE", -500},
+			},
+		},
+		{
+			name: "separators and encoding",
+			mapping: CSVMapping{
+				Comma:              ';',
+				Encoding:           Windows1252,
+				Header:             true,
+				Date:               " datum ",
+				DateLayout:         "02.01.2006",
+				Description:        "Omschrijving",
+				Amount:             "Bedrag",
+				DecimalSeparator:   ',',
+				ThousandsSeparator: '.',
+				Currency:           "EUR",
+			},
+			statement: "Datum;Omschrijving;Bedrag\n" +
+				"18.06.2022;Caf\xe9 \x80 ontbijt;-12,50\n" +
+				"20.06.2022;Salaris;\"1.234.567,89\"\n" +
+				"21.06.2022;Terugboeking;(7,00)\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "Café € ontbijt", -1250},
+				{"2022-06-20T00:00:00Z", "Salaris", 123456789},
+				{"2022-06-21T00:00:00Z", "Terugboeking", -700},
+			},
+		},
+		{
+			name:    "sign suffixes",
+			mapping: signed,
+			statement: "Date,Description,Amount\n" +
+				"2022-06-18,SUPERSPAR,236.19-\n" +
+				"2022-06-19,PICK N PAY,12.00 Dr\n" +
+				"2022-06-20,SALARY,15000.00CR\n" +
+				"2022-06-21,FEE,5.00dr\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "SUPERSPAR", -23619},
+				{"2022-06-19T00:00:00Z", "PICK N PAY", -1200},
+				{"2022-06-20T00:00:00Z", "SALARY", 1500000},
+				{"2022-06-21T00:00:00Z", "FEE", -500},
+			},
+		},
+		{
+			name: "find header and ignore undated rows",
+			mapping: CSVMapping{
+				Header:        true,
+				FindHeader:    true,
+				IgnoreUndated: true,
+				Date:          "Date",
+				DateLayout:    "2006-01-02",
+				Description:   "Description",
+				Amount:        "Amount",
+			},
+			statement: "Account,62001238911\n" +
+				"Name,Gold Account,Business\n" +
+				"Date,Description,Amount\n" +
+				"2022-06-18,SUPERSPAR,-236.19\n" +
+				"Closing balance,,24758.81\n" +
+				"2022-06-20,SALARY,abc\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "SUPERSPAR", -23619},
+			},
+			e: dutil.NewErr(400, "line 6", []string{"amount: invalid amount 'abc'"}),
+		},
+		{
+			name:      "header not found",
+			mapping:   CSVMapping{Header: true, FindHeader: true, Date: "Date", DateLayout: "2006-01-02", Amount: "Amount"},
+			statement: "Account,62001238911\nDate,Value\n",
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"date":   {"unknown column 'Date'"},
+					"amount": {"unknown column 'Amount'"},
+				},
+			},
+		},
+		{
+			name:    "blank rows",
+			mapping: signed,
+			statement: "Date,Description,Amount\n" +
+				"\n" +
+				",,\n" +
+				"2022-06-18,SUPERSPAR,-236.19\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "SUPERSPAR", -23619},
+			},
+		},
+		{
+			name:    "row errors with line numbers",
+			mapping: signed,
+			statement: "Date,Description,Amount\n" +
+				"2022-06-18,SUPERSPAR,-236.19\n" +
+				"18/06/2022,SUPERSPAR,-236.19\n" +
+				"\n" +
+				"2022-06-19,PICK N PAY,-12.00,\n" +
+				"2022-06-20,SALARY,15000.00\n" +
+				"2022-06-21,\"multi\nline\",abc\n",
+			rows: []row{
+				{"2022-06-18T00:00:00Z", "SUPERSPAR", -23619},
+				{"2022-06-19T00:00:00Z", "PICK N PAY", -1200},
+				{"2022-06-20T00:00:00Z", "SALARY", 1500000},
+			},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"line 3": {"date: invalid date '18/06/2022' for layout '2006-01-02'"},
+					"line 7": {"amount: invalid amount 'abc'"},
+				},
+			},
+		},
+		{
+			name:    "no debit or credit",
+			mapping: CSVMapping{Date: "1", DateLayout: "2006-01-02", Debit: "2", Credit: "3"},
+			statement: "2022-06-18,,\n" +
+				"2022-06-19,1.00,x\n",
+			rows: []row{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"line 1": {"amount: no debit or credit"},
+					"line 2": {"credit: invalid amount 'x'"},
+				},
+			},
+		},
+		{
+			name:      "unknown column",
+			mapping:   CSVMapping{Header: true, Date: "Date", DateLayout: "2006-01-02", Amount: "Value"},
+			statement: "Date,Amount\n",
+			e:         dutil.NewErr(400, "amount", []string{"unknown column 'Value'"}),
+		},
+		{
+			name:      "invalid mapping",
+			mapping:   CSVMapping{Amount: "2", Debit: "3"},
+			statement: "",
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"date":        {"required field"},
+					"date_layout": {"required field"},
+					"amount":      {"only one of amount and debit and credit may be set"},
+				},
+			},
+		},
+		{
+			name:      "no header row",
+			mapping:   signed,
+			statement: "",
+			e:         dutil.NewErr(400, "csv", []string{"unexpected end of the statement"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			xt, e := ParseCSV(strings.NewReader(tc.statement), accountUUID, tc.mapping)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && !errors.Is(e, ErrValidation) {
+				t.Errorf("expected a validation error got %v", e)
+			}
+			if len(xt) != len(tc.rows) {
+				t.Fatalf("expected %d transactions got %d", len(tc.rows), len(xt))
+			}
+			for j, r := range tc.rows {
+				txn := xt[j]
+				if txn.AccountUUID != accountUUID {
+					t.Errorf("expected bank account %s got %s", accountUUID, txn.AccountUUID)
+				}
+				if !txn.Date.Equal(timeMustParse(r.date)) {
+					t.Errorf("expected date %s got %s", r.date, txn.Date)
+				}
+				if txn.Description != r.description || len(txn.Items) != 1 || txn.Items[0].Description != r.description {
+					t.Errorf("expected description %s got %v", r.description, txn)
+				}
+				if len(txn.Items) == 1 && txn.Items[0].Amount.MinorUnits != r.amount {
+					t.Errorf("expected amount %d got %d", r.amount, txn.Items[0].Amount.MinorUnits)
+				}
+				if e := txn.Validate(); e != nil {
+					t.Errorf("expected a valid transaction got %v", e)
+				}
+			}
+		})
+	}
+}
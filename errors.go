@@ -0,0 +1,113 @@
+package bankserv
+
+import (
+	"errors"
+	"github.com/dottics/dutil"
+)
+
+// The kinds of errors returned by the Service methods. Use errors.Is to test
+// the kind of an error, for example:
+//
+//	ba, e := s.GetBankAccount(UUID)
+//	if errors.Is(e, ErrNotFound) {
+//		// the bank account does not exist
+//	}
+var (
+	// ErrNotFound is the kind of error of a resource that does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden is the kind of error of an exchange which the user does
+	// not have permission for.
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnauthorized is the kind of error of an exchange without a valid
+	// token.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrValidation is the kind of error of invalid data, the errors of the
+	// Error are keyed by the field which is invalid.
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict is the kind of error of data which conflicts with the
+	// current state of a resource.
+	ErrConflict = errors.New("conflict")
+	// ErrTransport is the kind of error of an exchange which did not reach
+	// the bank-service, the request failed, the context is done or a gateway
+	// returned an error.
+	ErrTransport = errors.New("transport failed")
+	// ErrSkipped is the kind of error of a transaction of CreateTransactions
+	// which was not created, because the create stopped at an error.
+	ErrSkipped = errors.New("skipped")
+)
+
+// Error is the error returned by the Service methods. Error embeds the
+// dutil.Err with the status and errors of the exchange, such that it is a
+// dutil.Error, and has the kind of the error which is used by errors.Is.
+//
+// Use errors.As to access the status and errors of an error:
+//
+//	var se *Error
+//	if errors.As(e, &se) && errors.Is(se, ErrValidation) {
+//		log.Println(se.Errors["account_number"])
+//	}
+type Error struct {
+	*dutil.Err
+	kind error
+}
+
+// Is reports whether the error is of the kind target.
+func (e *Error) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// Unwrap returns the underlying dutil.Err of the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError wraps the dutil.Error passed to the function in an Error with the
+// kind of the error based on its status and keys. If the error is nil or
+// already an Error it is returned as is.
+func newError(e dutil.Error) dutil.Error {
+	if e == nil {
+		return nil
+	}
+	if se, ok := e.(*Error); ok {
+		return se
+	}
+	err := dutil.Inst(e)
+	return &Error{
+		Err:  err,
+		kind: errorKind(err),
+	}
+}
+
+// errorKind returns the kind of the error, or nil if the error is not of
+// any of the kinds.
+func errorKind(e *dutil.Err) error {
+	if _, ok := e.Errors["request"]; ok {
+		return ErrTransport
+	}
+	if _, ok := e.Errors["context"]; ok {
+		return ErrTransport
+	}
+	if _, ok := e.Errors["skipped"]; ok {
+		return ErrSkipped
+	}
+	if _, ok := e.Errors["response"]; ok {
+		// the response is not from the bank-service
+		switch e.Status {
+		case 502, 503, 504:
+			return ErrTransport
+		}
+	}
+	switch e.Status {
+	case 400, 422:
+		return ErrValidation
+	case 401:
+		return ErrUnauthorized
+	case 403:
+		return ErrForbidden
+	case 404:
+		return ErrNotFound
+	case 409:
+		return ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package bankserv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+func TestNewError(t *testing.T) {
+	tt := []struct {
+		name string
+		e    dutil.Error
+		kind error
+	}{
+		{
+			name: "bad request",
+			e:    dutil.NewErr(400, "account_number", []string{"required field"}),
+			kind: ErrValidation,
+		},
+		{
+			name: "unprocessable entity",
+			e:    dutil.NewErr(422, "date", []string{"invalid date"}),
+			kind: ErrValidation,
+		},
+		{
+			name: "unauthorized",
+			e:    dutil.NewErr(401, "token", []string{"invalid token"}),
+			kind: ErrUnauthorized,
+		},
+		{
+			name: "forbidden",
+			e:    dutil.NewErr(403, "permission", []string{"Please ensure you have permission"}),
+			kind: ErrForbidden,
+		},
+		{
+			name: "not found",
+			e:    dutil.NewErr(404, "bank_account", []string{"not found"}),
+			kind: ErrNotFound,
+		},
+		{
+			name: "conflict",
+			e:    dutil.NewErr(409, "account_number", []string{"already exists"}),
+			kind: ErrConflict,
+		},
+		{
+			name: "request failed",
+			e:    dutil.NewErr(500, "request", []string{"connection refused"}),
+			kind: ErrTransport,
+		},
+		{
+			name: "context cancelled",
+			e:    dutil.NewErr(499, "context", []string{"context canceled"}),
+			kind: ErrTransport,
+		},
+		{
+			name: "gateway error",
+			e:    dutil.NewErr(502, "response", []string{"Bad Gateway"}),
+			kind: ErrTransport,
+		},
+		{
+			name: "internal server error",
+			e:    dutil.NewErr(500, "internal", []string{"unexpected error"}),
+			kind: nil,
+		},
+	}
+
+	kinds := []error{ErrNotFound, ErrForbidden, ErrUnauthorized, ErrValidation, ErrConflict, ErrTransport}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := newError(tc.e)
+			for _, kind := range kinds {
+				if errors.Is(e, kind) != (kind == tc.kind) {
+					t.Errorf("expected errors.Is(%v) to be %t", kind, kind == tc.kind)
+				}
+			}
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if dutil.Inst(e).Status != dutil.Inst(tc.e).Status {
+				t.Errorf("expected status %d got %d", dutil.Inst(tc.e).Status, dutil.Inst(e).Status)
+			}
+			var se *Error
+			if !errors.As(e, &se) {
+				t.Fatalf("expected error to be an *Error")
+			}
+			var de *dutil.Err
+			if !errors.As(e, &de) || de != dutil.Inst(tc.e) {
+				t.Errorf("expected error to wrap %v", tc.e)
+			}
+		})
+	}
+
+	t.Run("nil error", func(t *testing.T) {
+		if e := newError(nil); e != nil {
+			t.Errorf("expected nil got %v", e)
+		}
+	})
+
+	t.Run("wrap once", func(t *testing.T) {
+		e := newError(dutil.NewErr(404, "bank", []string{"not found"}))
+		if newError(e) != e {
+			t.Errorf("expected an Error to be returned as is")
+		}
+	})
+}
+
+func TestService_errors(t *testing.T) {
+	UUID := uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb")
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		f        func(s *Service) dutil.Error
+		kind     error
+	}{
+		{
+			name: "bank not found",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 404,
+				Body:   `{"message":"NotFound: unable to find resource","data":{},"errors":{"bank":["not found"]}}`,
+			}},
+			f: func(s *Service) dutil.Error {
+				_, e := s.GetBank(UUID)
+				return e
+			},
+			kind: ErrNotFound,
+		},
+		{
+			name: "bank account not found",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 200,
+				Body:   `{"message":"bank account found","data":{"bank_account":{}},"errors":{}}`,
+			}},
+			f: func(s *Service) dutil.Error {
+				_, e := s.GetBankAccount(UUID)
+				return e
+			},
+			kind: ErrNotFound,
+		},
+		{
+			name: "create bank account forbidden",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 403,
+				Body:   `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+			}},
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateBankAccount(userBankAccount)
+				return e
+			},
+			kind: ErrForbidden,
+		},
+		{
+			name: "update transaction invalid",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 400,
+				Body:   `{"message":"BadRequest: Unable to process request","data":{},"errors":{"uuid":["required field"]}}`,
+			}},
+			f: func(s *Service) dutil.Error {
+				_, e := s.UpdateTransaction(superspar)
+				return e
+			},
+			kind: ErrValidation,
+		},
+		{
+			name: "delete transaction unauthorized",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 401,
+				Body:   `{"message":"Unauthorized","data":{},"errors":{"token":["invalid token"]}}`,
+			}},
+			f: func(s *Service) dutil.Error {
+				return s.DeleteTransaction(UUID)
+			},
+			kind: ErrUnauthorized,
+		},
+		{
+			name: "create bank conflict",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 409,
+				Body:   `{"message":"Conflict","data":{},"errors":{"name":["already exists"]}}`,
+			}},
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateBank(investec)
+				return e
+			},
+			kind: ErrConflict,
+		},
+		{
+			name: "gateway error",
+			exchange: &microtest.Exchange{Response: microtest.Response{
+				Status: 504,
+				Body:   `<html>Gateway Timeout</html>`,
+			}},
+			f: func(s *Service) dutil.Error {
+				_, e := s.GetBanks()
+				return e
+			},
+			kind: ErrTransport,
+		},
+		{
+			name: "context cancelled",
+			f: func(s *Service) dutil.Error {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, e := s.GetBankAccountTransactionsContext(ctx, UUID)
+				return e
+			},
+			kind: ErrTransport,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := NewService("")
+			ms := microtest.MockServer(s.serv)
+			if tc.exchange != nil {
+				ms.Append(tc.exchange)
+			}
+
+			e := tc.f(s)
+			if !errors.Is(e, tc.kind) {
+				t.Errorf("expected error of kind %v got %v", tc.kind, e)
+			}
+		})
+	}
+}
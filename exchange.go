@@ -0,0 +1,152 @@
+package bankserv
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dottics/dutil"
+	"net/http"
+	"net/url"
+)
+
+// envelope is the structure of every response from the bank-service.
+type envelope struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Errors  dutil.Errors    `json:"errors"`
+}
+
+// Response holds the details of an exchange with the bank-service which are
+// not part of the data, such as the message from the bank-service. The Method
+// and Path are those of the request of the exchange.
+type Response struct {
+	Method  string
+	Path    string
+	Status  int
+	Message string
+	Header  http.Header
+}
+
+// SetResponseHandler sets the function which is called with the details of
+// every response from the bank-service, to have access to the message and
+// headers of the bank-service, for example:
+//
+//	s.SetResponseHandler(func(ctx context.Context, res Response) {
+//		log.Printf("%s %s: %d %s", res.Method, res.Path, res.Status, res.Message)
+//	})
+//
+// The context is the context of the exchange. The handler is called from the
+// goroutine of the exchange, therefore, it must be safe for concurrent use if
+// the Service is used by more than one goroutine. A response which is not
+// from the bank-service, such as a gateway error, is not passed to the
+// handler. SetResponseHandler should be called before the Service is used by
+// more than one goroutine, a nil handler removes the handler.
+func (s *Service) SetResponseHandler(h func(ctx context.Context, res Response)) {
+	s.onResponse = h
+}
+
+// exchange describes a single request to the bank-service and the response
+// expected from the bank-service.
+type exchange struct {
+	method string
+	path   string
+	query  url.Values
+	// header is added to the default headers of the request
+	header http.Header
+	// payload is marshalled to JSON as the body of the request, if set
+	payload interface{}
+	// status is the status code of a successful response
+	status int
+	// key is the key of the data to decode into data, if key is empty then
+	// the data is decoded into data as a whole
+	key  string
+	data interface{}
+}
+
+// do executes the exchange with the bank-service. The response is decoded
+// from the {message, data, errors} envelope and if the status of the response
+// is the expected status the data is decoded into the exchange's data,
+// otherwise the errors from the bank-service are returned as an Error.
+func (s *Service) do(ctx context.Context, x exchange) dutil.Error {
+	return newError(s.roundTrip(ctx, x))
+}
+
+// roundTrip executes the exchange for do.
+func (s *Service) roundTrip(ctx context.Context, x exchange) dutil.Error {
+	var p []byte
+	if x.payload != nil {
+		var err error
+		p, err = json.Marshal(x.payload)
+		if err != nil {
+			return dutil.NewErr(500, "marshal", []string{err.Error()})
+		}
+	}
+
+	res, e := s.send(ctx, x.method, s.url(x.path, x.query), x.header, p)
+	if e != nil {
+		return e
+	}
+	xb, e := s.decode(ctx, res)
+	if e != nil {
+		return e
+	}
+
+	env := envelope{}
+	err := json.Unmarshal(xb, &env)
+	if err != nil {
+		if res.StatusCode == x.status {
+			return dutil.NewErr(500, "unmarshal", []string{err.Error()})
+		}
+		// not every failed response is from the bank-service, for example
+		// a gateway error, then the status is the only information
+		return dutil.NewErr(res.StatusCode, "response", []string{http.StatusText(res.StatusCode)})
+	}
+	if s.onResponse != nil {
+		s.onResponse(ctx, Response{
+			Method:  x.method,
+			Path:    x.path,
+			Status:  res.StatusCode,
+			Message: env.Message,
+			Header:  res.Header,
+		})
+	}
+
+	if res.StatusCode != x.status {
+		e := &dutil.Err{
+			Status: res.StatusCode,
+			Errors: env.Errors,
+		}
+		return e
+	}
+	return decodeData(env.Data, x.key, x.data)
+}
+
+// decodeData decodes the data of the envelope into v. If a key is passed to
+// the function only the value of the key is decoded into v.
+func decodeData(data json.RawMessage, key string, v interface{}) dutil.Error {
+	if v == nil || len(data) == 0 {
+		return nil
+	}
+	if key != "" {
+		m := make(map[string]json.RawMessage)
+		err := json.Unmarshal(data, &m)
+		if err != nil {
+			return dutil.NewErr(500, "unmarshal", []string{err.Error()})
+		}
+		var ok bool
+		data, ok = m[key]
+		if !ok {
+			return nil
+		}
+	}
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return dutil.NewErr(500, "unmarshal", []string{err.Error()})
+	}
+	return nil
+}
+
+// notFound returns the error for a resource that does not exist, which has
+// the same status and structure as the not found errors of the bank-service.
+func notFound(resource string) dutil.Error {
+	return newError(dutil.NewErr(404, resource, []string{"not found"}))
+}
@@ -0,0 +1,224 @@
+package bankserv
+
+import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+func TestService_do(t *testing.T) {
+	type data struct {
+		Name string `json:"name"`
+	}
+	tt := []struct {
+		name     string
+		x        exchange
+		exchange *microtest.Exchange
+		data     data
+		response Response
+		e        dutil.Error
+	}{
+		{
+			name: "unexpected status",
+			x: exchange{
+				method: "GET",
+				path:   "/bank",
+				status: 200,
+				key:    "bank",
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body:   `{"message":"Forbidden: Unable to process request","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+				},
+			},
+			response: Response{
+				Status:  403,
+				Message: "Forbidden: Unable to process request",
+			},
+			e: &dutil.Err{
+				Status: 403,
+				Errors: map[string][]string{
+					"permission": {"Please ensure you have permission"},
+				},
+			},
+		},
+		{
+			name: "response not from the bank-service",
+			x: exchange{
+				method: "GET",
+				path:   "/bank",
+				status: 200,
+				key:    "bank",
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 502,
+					Body:   `<html>Bad Gateway</html>`,
+				},
+			},
+			e: dutil.NewErr(502, "response", []string{"Bad Gateway"}),
+		},
+		{
+			name: "invalid successful response",
+			x: exchange{
+				method: "GET",
+				path:   "/bank",
+				status: 200,
+				key:    "bank",
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"data":`,
+				},
+			},
+			e: dutil.NewErr(500, "unmarshal", []string{"unexpected end of JSON input"}),
+		},
+		{
+			name: "data by key",
+			x: exchange{
+				method:  "POST",
+				path:    "/bank",
+				payload: data{Name: "investec"},
+				status:  201,
+				key:     "bank",
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 201,
+					Body:   `{"message":"bank created","data":{"bank":{"name":"investec"}},"errors":{}}`,
+				},
+			},
+			data: data{Name: "investec"},
+			response: Response{
+				Status:  201,
+				Message: "bank created",
+			},
+		},
+		{
+			name: "data as a whole",
+			x: exchange{
+				method: "GET",
+				path:   "/bank",
+				status: 200,
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"bank found","data":{"name":"investec"},"errors":{}}`,
+				},
+			},
+			data: data{Name: "investec"},
+			response: Response{
+				Status:  200,
+				Message: "bank found",
+			},
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			d := data{}
+			tc.x.data = &d
+			res := Response{}
+			s.SetResponseHandler(func(ctx context.Context, r Response) {
+				res = r
+			})
+			e := s.do(context.Background(), tc.x)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && dutil.Inst(e).Status != dutil.Inst(tc.e).Status {
+				t.Errorf("expected status %d got %d", dutil.Inst(tc.e).Status, dutil.Inst(e).Status)
+			}
+			if d != tc.data {
+				t.Errorf("expected data %v got %v", tc.data, d)
+			}
+			if res.Status != tc.response.Status {
+				t.Errorf("expected response status %d got %d", tc.response.Status, res.Status)
+			}
+			if res.Message != tc.response.Message {
+				t.Errorf("expected response message '%s' got '%s'", tc.response.Message, res.Message)
+			}
+			if res.Status != 0 && (res.Method != tc.x.method || res.Path != tc.x.path) {
+				t.Errorf("expected response of %s %s got %s %s", tc.x.method, tc.x.path, res.Method, res.Path)
+			}
+			if tc.exchange.Request.Method != tc.x.method {
+				t.Errorf("expected method %s got %s", tc.x.method, tc.exchange.Request.Method)
+			}
+		})
+	}
+}
+
+func TestDecodeData(t *testing.T) {
+	tt := []struct {
+		name string
+		data string
+		key  string
+		o    Banks
+		// the key of the expected error
+		e string
+	}{
+		{
+			name: "no data",
+			data: ``,
+			key:  "banks",
+			o:    nil,
+		},
+		{
+			name: "key not in data",
+			data: `{}`,
+			key:  "banks",
+			o:    nil,
+		},
+		{
+			name: "data is not an object",
+			data: `[]`,
+			key:  "banks",
+			o:    nil,
+			e:    "unmarshal",
+		},
+		{
+			name: "data by key",
+			data: `{"banks":[{"name":"investec"}]}`,
+			key:  "banks",
+			o:    Banks{{Name: "investec"}},
+		},
+		{
+			name: "data as a whole",
+			data: `[{"name":"investec"}]`,
+			o:    Banks{{Name: "investec"}},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			var xb Banks
+			e := decodeData([]byte(tc.data), tc.key, &xb)
+			if tc.e == "" && e != nil {
+				t.Errorf("unexpected error %v", e)
+			}
+			if tc.e != "" && dutil.Inst(e).Errors[tc.e] == nil {
+				t.Errorf("expected error with key %s got %v", tc.e, e)
+			}
+			if len(xb) != len(tc.o) {
+				t.Fatalf("expected %d banks got %d", len(tc.o), len(xb))
+			}
+			for i, b := range xb {
+				if b != tc.o[i] {
+					t.Errorf("expected bank %v got %v", tc.o[i], b)
+				}
+			}
+		})
+	}
+}
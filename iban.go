@@ -0,0 +1,109 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"strings"
+)
+
+// ibanLengths is the length of the IBANs of every country in the IBAN
+// registry, keyed by the ISO 3166-1 alpha-2 country code.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16,
+	"BG": 22, "BH": 22, "BI": 27, "BR": 29, "BY": 28, "CH": 21, "CR": 22,
+	"CY": 28, "CZ": 24, "DE": 22, "DJ": 27, "DK": 18, "DO": 28, "EE": 20,
+	"EG": 29, "ES": 24, "FI": 18, "FK": 18, "FO": 18, "FR": 27, "GB": 22,
+	"GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28,
+	"IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30,
+	"KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21,
+	"LY": 25, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27,
+	"MT": 31, "MU": 30, "NI": 28, "NL": 18, "NO": 15, "OM": 23, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33,
+	"SA": 24, "SC": 31, "SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27,
+	"SO": 23, "ST": 25, "SV": 28, "TL": 23, "TN": 24, "TR": 26, "UA": 29,
+	"VA": 22, "VG": 24, "XK": 20, "YE": 30,
+}
+
+// ParseIBAN parses an International Bank Account Number (IBAN) in either the
+// electronic format, "GB82WEST12345698765432", or the print format,
+// "GB82 WEST 1234 5698 7654 32", and returns the IBAN in the electronic
+// format.
+//
+// The IBAN is valid if the country is in the IBAN registry, the IBAN has the
+// length of the country and the mod-97 checksum of the IBAN is 1. If the IBAN
+// is invalid an error of the kind ErrValidation with the key "iban" is
+// returned.
+func ParseIBAN(s string) (string, dutil.Error) {
+	iban := strings.ToUpper(strings.Replace(strings.TrimSpace(s), " ", "", -1))
+	reason := ibanReason(iban)
+	if reason != "" {
+		v := validation{}
+		v.add("iban", reason)
+		return "", v.err()
+	}
+	return iban, nil
+}
+
+// ibanReason returns the reason the IBAN in the electronic format is invalid,
+// or an empty string if the IBAN is valid.
+func ibanReason(iban string) string {
+	if len(iban) < 5 {
+		return fmt.Sprintf("invalid IBAN '%s'", iban)
+	}
+	for _, c := range iban {
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return "must only contain letters and digits"
+		}
+	}
+	country := iban[:2]
+	n, ok := ibanLengths[country]
+	if !ok {
+		return fmt.Sprintf("unknown IBAN country '%s'", country)
+	}
+	if len(iban) != n {
+		return fmt.Sprintf("must be %d characters for %s", n, country)
+	}
+	if !isDigit(iban[2]) || !isDigit(iban[3]) {
+		return "invalid check digits"
+	}
+	if ibanMod97(iban) != 1 {
+		return "checksum does not match"
+	}
+	return ""
+}
+
+// ibanMod97 returns the remainder of the IBAN as a number divided by 97. The
+// first four characters are moved to the end of the IBAN and every letter is
+// replaced by two digits, where A is 10 and Z is 35.
+func ibanMod97(iban string) int {
+	r := 0
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			r = (r*100 + int(c-'A') + 10) % 97
+		} else {
+			r = (r*10 + int(c-'0')) % 97
+		}
+	}
+	return r
+}
+
+// FormatIBAN formats the IBAN in the print format, the IBAN in groups of four
+// characters separated by spaces, for example "GB82 WEST 1234 5698 7654 32".
+// The IBAN is not validated.
+func FormatIBAN(iban string) string {
+	s := strings.ToUpper(strings.Replace(strings.TrimSpace(iban), " ", "", -1))
+	var b strings.Builder
+	n := 0
+	for _, c := range s {
+		if n > 0 && n%4 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(c)
+		n++
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
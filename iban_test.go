@@ -0,0 +1,128 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"testing"
+)
+
+func TestParseIBAN(t *testing.T) {
+	tt := []struct {
+		name string
+		s    string
+		iban string
+		e    dutil.Error
+	}{
+		{
+			name: "electronic format",
+			s:    "GB82WEST12345698765432",
+			iban: "GB82WEST12345698765432",
+			e:    nil,
+		},
+		{
+			name: "print format",
+			s:    " GB82 WEST 1234 5698 7654 32 ",
+			iban: "GB82WEST12345698765432",
+			e:    nil,
+		},
+		{
+			name: "lower case",
+			s:    "de89 3704 0044 0532 0130 00",
+			iban: "DE89370400440532013000",
+			e:    nil,
+		},
+		{
+			name: "shortest country",
+			s:    "NO9386011117947",
+			iban: "NO9386011117947",
+			e:    nil,
+		},
+		{
+			name: "checksum",
+			s:    "GB82WEST12345698765433",
+			e:    dutil.NewErr(400, "iban", []string{"checksum does not match"}),
+		},
+		{
+			name: "swapped digits",
+			s:    "GB82WEST12345698765423",
+			e:    dutil.NewErr(400, "iban", []string{"checksum does not match"}),
+		},
+		{
+			name: "country length",
+			s:    "GB82WEST1234569876543",
+			e:    dutil.NewErr(400, "iban", []string{"must be 22 characters for GB"}),
+		},
+		{
+			name: "unknown country",
+			s:    "ZA82WEST12345698765432",
+			e:    dutil.NewErr(400, "iban", []string{"unknown IBAN country 'ZA'"}),
+		},
+		{
+			name: "check digits",
+			s:    "GBX2WEST12345698765432",
+			e:    dutil.NewErr(400, "iban", []string{"invalid check digits"}),
+		},
+		{
+			name: "invalid characters",
+			s:    "GB82-WEST-1234-5698-7654-32",
+			e:    dutil.NewErr(400, "iban", []string{"must only contain letters and digits"}),
+		},
+		{
+			name: "too short",
+			s:    "GB8",
+			e:    dutil.NewErr(400, "iban", []string{"invalid IBAN 'GB8'"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			iban, e := ParseIBAN(tc.s)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if iban != tc.iban {
+				t.Errorf("expected IBAN %s got %s", tc.iban, iban)
+			}
+		})
+	}
+}
+
+func TestFormatIBAN(t *testing.T) {
+	tt := []struct {
+		name string
+		iban string
+		s    string
+	}{
+		{
+			name: "complete groups",
+			iban: "DE89370400440532013000",
+			s:    "DE89 3704 0044 0532 0130 00",
+		},
+		{
+			name: "print format",
+			iban: "gb82 west 1234 5698 7654 32",
+			s:    "GB82 WEST 1234 5698 7654 32",
+		},
+		{
+			name: "multiple of four",
+			iban: "BE68539007547034",
+			s:    "BE68 5390 0754 7034",
+		},
+		{
+			name: "empty",
+			iban: "",
+			s:    "",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := FormatIBAN(tc.iban)
+			if s != tc.s {
+				t.Errorf("expected %s got %s", tc.s, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package bankserv
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotencyHeader is the header which carries the idempotency key of a
+// create to the bank-service.
+const idempotencyHeader = "Idempotency-Key"
+
+// idempotencyTTL is how long the result of a create with an idempotency key
+// supplied by the caller is kept by the Service.
+const idempotencyTTL = 24 * time.Hour
+
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a copy of the context which carries the
+// idempotency key passed to the function. Pass the context to
+// CreateTransactionContext or CreateBankAccountContext and use the same key
+// to retry a create of which the response was lost, for example:
+//
+//	ctx = WithIdempotencyKey(ctx, key)
+//	txn, e := s.CreateTransactionContext(ctx, t)
+//	if e != nil {
+//		// retry with the same key, the transaction is only created once
+//		txn, e = s.CreateTransactionContext(ctx, t)
+//	}
+//
+// If the context does not carry an idempotency key, a new key is generated
+// for every create.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// idempotencyKey returns the idempotency key of the context and true, if the
+// context does not carry a key a new key and false is returned.
+func idempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyKey{}).(string)
+	if ok && key != "" {
+		return key, true
+	}
+	return uuid.New().String(), false
+}
+
+// idempotentCall is a create with an idempotency key which is either in
+// flight or completed successfully.
+type idempotentCall struct {
+	done    chan struct{}
+	expires time.Time
+	v       interface{}
+	e       dutil.Error
+}
+
+// idempotencyRecord is the client-side record of the creates with an
+// idempotency key supplied by the caller.
+type idempotencyRecord struct {
+	mu    sync.Mutex
+	calls map[string]*idempotentCall
+}
+
+// idempotent executes the create fn with an idempotency key header. If the
+// caller supplied the idempotency key through the context, the create is
+// executed at most once per key at a time:
+//   - a create with the key of a create in flight waits for that create and
+//     returns its result,
+//   - a create with the key of a successful create returns the original
+//     result without an exchange with the bank-service,
+//   - a create with the key of a failed create is sent again with the same
+//     key, such that the bank-service is able to detect the duplicate.
+//
+// The resource is part of the record's key, such that the same key is able
+// to be used for different types of resources.
+func (s *Service) idempotent(ctx context.Context, resource string, fn func(header http.Header) (interface{}, dutil.Error)) (interface{}, dutil.Error) {
+	key, supplied := idempotencyKey(ctx)
+	header := http.Header{idempotencyHeader: {key}}
+	if !supplied {
+		return fn(header)
+	}
+
+	r := &s.idempotency
+	k := resource + " " + key
+	for {
+		r.mu.Lock()
+		r.prune(time.Now())
+		c, ok := r.calls[k]
+		if !ok {
+			break
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, newError(contextError(ctx))
+		}
+		if c.e == nil {
+			return c.v, nil
+		}
+		// the create failed and was removed from the record, try again
+	}
+
+	c := &idempotentCall{done: make(chan struct{})}
+	if r.calls == nil {
+		r.calls = make(map[string]*idempotentCall)
+	}
+	r.calls[k] = c
+	r.mu.Unlock()
+
+	c.v, c.e = fn(header)
+
+	r.mu.Lock()
+	if c.e != nil {
+		delete(r.calls, k)
+	} else {
+		c.expires = time.Now().Add(idempotencyTTL)
+	}
+	r.mu.Unlock()
+	close(c.done)
+	return c.v, c.e
+}
+
+// forget removes the completed create of the resource with the key from the
+// record, such that the next create with the key is sent to the bank-service.
+// A create which is in flight is not removed.
+func (r *idempotencyRecord) forget(resource, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := resource + " " + key
+	if c, ok := r.calls[k]; ok && !c.expires.IsZero() {
+		delete(r.calls, k)
+	}
+}
+
+// prune removes the completed creates which have expired from the record.
+// The lock of the record must be held.
+func (r *idempotencyRecord) prune(now time.Time) {
+	for k, c := range r.calls {
+		if !c.expires.IsZero() && now.After(c.expires) {
+			delete(r.calls, k)
+		}
+	}
+}
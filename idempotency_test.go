@@ -0,0 +1,253 @@
+package bankserv
+
+import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+const createdTransactionJSON = `{"message":"transaction created","data":{"transaction":{"uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","date":"2022-06-18T15:26:22Z","description":"SUPERSPAR JEFFREYS BAYEASTERN CAPEZA","items":[],"active":true}},"errors":{}}`
+
+// duplicateTransactionJSON is the response of a second create, which the
+// tests expect to never be sent.
+const duplicateTransactionJSON = `{"message":"transaction created","data":{"transaction":{"uuid":"d25ac3b1-0a8f-43a3-8da1-d2f22a814a82","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","date":"2022-06-18T15:26:22Z","description":"SUPERSPAR JEFFREYS BAYEASTERN CAPEZA","items":[],"active":true}},"errors":{}}`
+
+var superspar = Transaction{
+	AccountUUID: uuid.MustParse("032203af-6002-4abc-9982-73c577add8df"),
+	Date:        timeMustParse("2022-06-18T15:26:22Z"),
+	Description: "SUPERSPAR JEFFREYS BAYEASTERN CAPEZA",
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	key, ok := idempotencyKey(WithIdempotencyKey(context.Background(), "abc"))
+	if !ok || key != "abc" {
+		t.Errorf("expected supplied key %s got %s %t", "abc", key, ok)
+	}
+
+	k1, ok := idempotencyKey(context.Background())
+	if ok {
+		t.Errorf("expected generated key")
+	}
+	if _, err := uuid.Parse(k1); err != nil {
+		t.Errorf("expected generated key to be a UUID got %s", k1)
+	}
+	k2, _ := idempotencyKey(WithIdempotencyKey(context.Background(), ""))
+	if k1 == k2 {
+		t.Errorf("expected a new key for every create got %s twice", k1)
+	}
+}
+
+func TestService_CreateTransaction_idempotency(t *testing.T) {
+	t.Run("generated key for every create", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		x1 := &microtest.Exchange{Response: microtest.Response{Status: 201, Body: createdTransactionJSON}}
+		x2 := &microtest.Exchange{Response: microtest.Response{Status: 201, Body: duplicateTransactionJSON}}
+		ms.Append(x1)
+		ms.Append(x2)
+
+		_, _ = s.CreateTransaction(superspar)
+		_, _ = s.CreateTransaction(superspar)
+		k1 := x1.Request.Header.Get("Idempotency-Key")
+		k2 := x2.Request.Header.Get("Idempotency-Key")
+		if k1 == "" || k1 == k2 {
+			t.Errorf("expected a unique key for every create got '%s' and '%s'", k1, k2)
+		}
+	})
+
+	t.Run("lost response", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		ms.Server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		// the connection is dropped before the response is received, the
+		// caller is unable to tell whether the transaction was created
+		lost := &microtest.Exchange{Response: connectionReset}
+		created := &microtest.Exchange{Response: microtest.Response{Status: 201, Body: createdTransactionJSON}}
+		duplicate := &microtest.Exchange{Response: microtest.Response{Status: 201, Body: duplicateTransactionJSON}}
+		ms.Append(lost)
+		ms.Append(created)
+		ms.Append(duplicate)
+
+		ctx := WithIdempotencyKey(context.Background(), "superspar-2022-06-18")
+		_, e := s.CreateTransactionContext(ctx, superspar)
+		if _, ok := dutil.Inst(e).Errors["request"]; !ok {
+			t.Fatalf("expected request error got %v", e)
+		}
+
+		txn, e := s.CreateTransactionContext(ctx, superspar)
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		if txn.UUID.String() != "e4bd194d-41e7-4f27-a4a8-161685a9b8b8" {
+			t.Errorf("expected transaction %s got %s", "e4bd194d-41e7-4f27-a4a8-161685a9b8b8", txn.UUID)
+		}
+		for i, x := range []*microtest.Exchange{lost, created} {
+			k := x.Request.Header.Get("Idempotency-Key")
+			if k != "superspar-2022-06-18" {
+				t.Errorf("expected exchange %d to have key %s got '%s'", i, "superspar-2022-06-18", k)
+			}
+		}
+
+		// the create completed, the original transaction is returned
+		// without a second exchange
+		txn, e = s.CreateTransactionContext(ctx, superspar)
+		if e != nil {
+			t.Errorf("unexpected error %v", e)
+		}
+		if txn.UUID.String() != "e4bd194d-41e7-4f27-a4a8-161685a9b8b8" {
+			t.Errorf("expected original transaction got %s", txn.UUID)
+		}
+		if duplicate.Request != nil {
+			t.Errorf("expected no exchange for a completed create")
+		}
+	})
+
+	t.Run("failed create is not recorded", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		ms.Append(&microtest.Exchange{Response: microtest.Response{
+			Status: 403,
+			Body:   `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+		}})
+		created := &microtest.Exchange{Response: microtest.Response{Status: 201, Body: createdTransactionJSON}}
+		ms.Append(created)
+
+		ctx := WithIdempotencyKey(context.Background(), "abc")
+		_, e := s.CreateTransactionContext(ctx, superspar)
+		ee := dutil.NewErr(403, "permission", []string{"Please ensure you have permission"})
+		if !dutil.ErrorEqual(ee, e) {
+			t.Errorf("expected error %v got %v", ee, e)
+		}
+		_, e = s.CreateTransactionContext(ctx, superspar)
+		if e != nil {
+			t.Errorf("unexpected error %v", e)
+		}
+		if created.Request == nil {
+			t.Errorf("expected the create to be sent again")
+		}
+	})
+
+	t.Run("same key for a different resource", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 201, Body: createdTransactionJSON}})
+		x := &microtest.Exchange{Response: microtest.Response{
+			Status: 201,
+			Body:   `{"message":"bank account created","data":{"bank_account":{"uuid":"e6b7f986-307c-4147-a34e-f924790799bb"}},"errors":{}}`,
+		}}
+		ms.Append(x)
+
+		ctx := WithIdempotencyKey(context.Background(), "abc")
+		_, _ = s.CreateTransactionContext(ctx, superspar)
+		ba, e := s.CreateBankAccountContext(ctx, userBankAccount)
+		if e != nil {
+			t.Errorf("unexpected error %v", e)
+		}
+		if x.Request == nil || ba.UUID != userBankAccount.UUID {
+			t.Errorf("expected bank account to be created got %v", ba)
+		}
+	})
+
+	t.Run("concurrent creates with the same key", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		var mu sync.Mutex
+		h := ms.Server.Config.Handler
+		ms.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// keep the create in flight while the other creates start
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			h.ServeHTTP(w, r)
+		})
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 201, Body: createdTransactionJSON}})
+		duplicate := &microtest.Exchange{Response: microtest.Response{Status: 201, Body: duplicateTransactionJSON}}
+		ms.Append(duplicate)
+
+		ctx := WithIdempotencyKey(context.Background(), "abc")
+		n := 5
+		xUUID := make([]uuid.UUID, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				txn, e := s.CreateTransactionContext(ctx, superspar)
+				if e != nil {
+					t.Errorf("unexpected error %v", e)
+				}
+				xUUID[i] = txn.UUID
+			}(i)
+		}
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if duplicate.Request != nil {
+			t.Errorf("expected a single exchange")
+		}
+		for i, UUID := range xUUID {
+			if UUID.String() != "e4bd194d-41e7-4f27-a4a8-161685a9b8b8" {
+				t.Errorf("expected create %d to return the original transaction got %s", i, UUID)
+			}
+		}
+	})
+}
+
+func TestService_CreateBankAccount_idempotency(t *testing.T) {
+	tt := []struct {
+		name string
+		key  string
+	}{
+		{
+			name: "generated key",
+			key:  "",
+		},
+		{
+			name: "supplied key",
+			key:  "fnb-cheque",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := NewService("")
+			s.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, RetryPOST: true})
+			ms := microtest.MockServer(s.serv)
+			unavailable := &microtest.Exchange{Response: microtest.Response{Status: 503, Body: `<html>Service Unavailable</html>`}}
+			created := &microtest.Exchange{Response: microtest.Response{
+				Status: 201,
+				Body:   `{"message":"bank account created","data":{"bank_account":{"uuid":"e6b7f986-307c-4147-a34e-f924790799bb"}},"errors":{}}`,
+			}}
+			ms.Append(unavailable)
+			ms.Append(created)
+
+			ctx := context.Background()
+			if tc.key != "" {
+				ctx = WithIdempotencyKey(ctx, tc.key)
+			}
+			_, e := s.CreateBankAccountContext(ctx, userBankAccount)
+			if e != nil {
+				t.Errorf("unexpected error %v", e)
+			}
+			// every attempt of a create carries the same key
+			k1 := unavailable.Request.Header.Get("Idempotency-Key")
+			k2 := created.Request.Header.Get("Idempotency-Key")
+			if k1 == "" || k1 != k2 {
+				t.Errorf("expected the same key for every attempt got '%s' and '%s'", k1, k2)
+			}
+			if tc.key != "" && k1 != tc.key {
+				t.Errorf("expected key %s got %s", tc.key, k1)
+			}
+		})
+	}
+}
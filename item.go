@@ -0,0 +1,191 @@
+package bankserv
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/url"
+)
+
+// GetItem gets a specific item based on the item's UUID passed to the
+// function. If the item does not exist an error with the status 404 is
+// returned.
+func (s *Service) GetItem(UUID uuid.UUID) (Item, dutil.Error) {
+	return s.GetItemContext(context.Background(), UUID)
+}
+
+// GetItemContext is the same as GetItem, the context passed to the function is
+// used to cancel the exchange with the bank-service.
+func (s *Service) GetItemContext(ctx context.Context, UUID uuid.UUID) (Item, dutil.Error) {
+	item := Item{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/item/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "item",
+		data:   &item,
+	})
+	if e != nil {
+		return Item{}, e
+	}
+	if item.UUID == uuid.Nil {
+		return Item{}, notFound("item")
+	}
+	return item, nil
+}
+
+// GetTransactionItems gets all the items of a specific transaction based on
+// the transaction's UUID passed to the function and returns a slice of Item.
+// If the transaction has no items an empty slice will be returned.
+func (s *Service) GetTransactionItems(UUID uuid.UUID) (Items, dutil.Error) {
+	return s.GetTransactionItemsContext(context.Background(), UUID)
+}
+
+// GetTransactionItemsContext is the same as GetTransactionItems, the context
+// passed to the function is used to cancel the exchange with the bank-service.
+func (s *Service) GetTransactionItemsContext(ctx context.Context, UUID uuid.UUID) (Items, dutil.Error) {
+	xi := Items{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/item/transaction/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "items",
+		data:   &xi,
+	})
+	if e != nil {
+		return Items{}, e
+	}
+	return xi, nil
+}
+
+// CreateItem creates a new item for the transaction with the item's
+// TransactionUUID, without sending the rest of the transaction, and returns
+// the item.
+func (s *Service) CreateItem(i Item) (Item, dutil.Error) {
+	return s.CreateItemContext(context.Background(), i)
+}
+
+// CreateItemContext is the same as CreateItem, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) CreateItemContext(ctx context.Context, i Item) (Item, dutil.Error) {
+	if e := i.Validate(); e != nil {
+		return Item{}, e
+	}
+	item := Item{}
+	e := s.do(ctx, exchange{
+		method:  "POST",
+		path:    "/item",
+		payload: i,
+		status:  201,
+		key:     "item",
+		data:    &item,
+	})
+	if e != nil {
+		return Item{}, e
+	}
+	return item, nil
+}
+
+// UpdateItem updates only the item with the item's UUID, the other items of
+// the transaction are left as is.
+func (s *Service) UpdateItem(i Item) (Item, dutil.Error) {
+	return s.UpdateItemContext(context.Background(), i)
+}
+
+// UpdateItemContext is the same as UpdateItem, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) UpdateItemContext(ctx context.Context, i Item) (Item, dutil.Error) {
+	if e := validateUpdate(i.UUID, i); e != nil {
+		return Item{}, e
+	}
+	item := Item{}
+	e := s.do(ctx, exchange{
+		method:  "PUT",
+		path:    "/item/-",
+		payload: i,
+		status:  200,
+		key:     "item",
+		data:    &item,
+	})
+	if e != nil {
+		return Item{}, e
+	}
+	return item, nil
+}
+
+// DeleteItem deletes a specific item from its transaction. It only returns an
+// error if an error has occurred.
+func (s *Service) DeleteItem(UUID uuid.UUID) dutil.Error {
+	return s.DeleteItemContext(context.Background(), UUID)
+}
+
+// DeleteItemContext is the same as DeleteItem, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) DeleteItemContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	return s.do(ctx, exchange{
+		method: "DELETE",
+		path:   "/item/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+	})
+}
+
+// AddItemTag tags a specific item with the tag from the catalogue with the
+// tag's UUID and returns the item with its tags.
+func (s *Service) AddItemTag(itemUUID, tagUUID uuid.UUID) (Item, dutil.Error) {
+	return s.AddItemTagContext(context.Background(), itemUUID, tagUUID)
+}
+
+// AddItemTagContext is the same as AddItemTag, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) AddItemTagContext(ctx context.Context, itemUUID, tagUUID uuid.UUID) (Item, dutil.Error) {
+	payload := struct {
+		ItemUUID uuid.UUID `json:"item_uuid"`
+		TagUUID  uuid.UUID `json:"tag_uuid"`
+	}{
+		ItemUUID: itemUUID,
+		TagUUID:  tagUUID,
+	}
+	item := Item{}
+	e := s.do(ctx, exchange{
+		method:  "POST",
+		path:    "/item/tag",
+		payload: payload,
+		status:  200,
+		key:     "item",
+		data:    &item,
+	})
+	if e != nil {
+		return Item{}, e
+	}
+	return item, nil
+}
+
+// RemoveItemTag removes the tag with the tag's UUID from a specific item and
+// returns the item with its remaining tags.
+func (s *Service) RemoveItemTag(itemUUID, tagUUID uuid.UUID) (Item, dutil.Error) {
+	return s.RemoveItemTagContext(context.Background(), itemUUID, tagUUID)
+}
+
+// RemoveItemTagContext is the same as RemoveItemTag, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) RemoveItemTagContext(ctx context.Context, itemUUID, tagUUID uuid.UUID) (Item, dutil.Error) {
+	item := Item{}
+	e := s.do(ctx, exchange{
+		method: "DELETE",
+		path:   "/item/tag",
+		query: url.Values{
+			"item_uuid": {itemUUID.String()},
+			"tag_uuid":  {tagUUID.String()},
+		},
+		status: 200,
+		key:    "item",
+		data:   &item,
+	})
+	if e != nil {
+		return Item{}, e
+	}
+	return item, nil
+}
@@ -0,0 +1,449 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+var milk = Item{
+	UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
+	TransactionUUID: uuid.MustParse("d441f6ba-4e40-477a-aa46-916b2dc56bb5"),
+	Description:     "milk",
+	SKU:             Units(1),
+	Amount:          Money{MinorUnits: 2499},
+	Discount:        Money{MinorUnits: 250},
+	Tags:            Tags{groceries},
+	Active:          true,
+	CreateDate:      timeMustParse("2022-06-20T08:00:00Z"),
+	UpdateDate:      timeMustParse("2022-06-20T08:00:00Z"),
+}
+
+const milkJSON = `{"uuid":"a03d4ac5-1d5b-465c-9e0a-c7658912c47d","transaction_uuid":"d441f6ba-4e40-477a-aa46-916b2dc56bb5","description":"milk","sku":1,"amount":24.99,"discount":2.50,"tags":[` + groceriesJSON + `],"active":true,"create_date":"2022-06-20T08:00:00Z","update_date":"2022-06-20T08:00:00Z"}`
+
+func TestService_GetTransactionItems(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		items    Items
+		e        dutil.Error
+	}{
+		{
+			name: "transaction not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"transaction":["not found"]}}`,
+				},
+			},
+			items: Items{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"transaction": {"not found"},
+				},
+			},
+		},
+		{
+			name: "items found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"items found","data":{"items":[` + milkJSON + `]},"errors":{}}`,
+				},
+			},
+			items: Items{milk},
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			xi, e := s.GetTransactionItems(milk.TransactionUUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualItems(tc.items, xi) {
+				t.Errorf("expected items %v got %v", tc.items, xi)
+			}
+			q := tc.exchange.Request.URL.Query().Get("uuid")
+			if q != milk.TransactionUUID.String() {
+				t.Errorf("expected query uuid %s got %s", milk.TransactionUUID.String(), q)
+			}
+		})
+	}
+}
+
+func TestService_CreateItem(t *testing.T) {
+	tt := []struct {
+		name     string
+		item     Item
+		exchange *microtest.Exchange
+		EItem    Item
+		e        dutil.Error
+	}{
+		{
+			name:  "bad request",
+			item:  Item{Description: "milk"},
+			EItem: Item{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"transaction_uuid": {"required field"},
+				},
+			},
+		},
+		{
+			name: "create item",
+			item: Item{
+				TransactionUUID: milk.TransactionUUID,
+				Description:     "milk",
+				SKU:             Units(1),
+				Amount:          Money{MinorUnits: 2499},
+				Discount:        Money{MinorUnits: 250},
+			},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 201,
+					Body:   `{"message":"item created","data":{"item":` + milkJSON + `},"errors":{}}`,
+				},
+			},
+			EItem: milk,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			if tc.exchange != nil {
+				ms.Append(tc.exchange)
+			}
+
+			item, e := s.CreateItem(tc.item)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualItem(tc.EItem, item) {
+				t.Errorf("expected item %v got %v", tc.EItem, item)
+			}
+		})
+	}
+}
+
+func TestService_UpdateItem(t *testing.T) {
+	tt := []struct {
+		name     string
+		item     Item
+		exchange *microtest.Exchange
+		EItem    Item
+		e        dutil.Error
+	}{
+		{
+			name: "item not found",
+			item: milk,
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"item":["not found"]}}`,
+				},
+			},
+			EItem: Item{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"item": {"not found"},
+				},
+			},
+		},
+		{
+			name: "update item",
+			item: milk,
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"item updated","data":{"item":` + milkJSON + `},"errors":{}}`,
+				},
+			},
+			EItem: milk,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			item, e := s.UpdateItem(tc.item)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualItem(tc.EItem, item) {
+				t.Errorf("expected item %v got %v", tc.EItem, item)
+			}
+		})
+	}
+}
+
+func TestService_DeleteItem(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		e        dutil.Error
+	}{
+		{
+			name: "permission required",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body:   `{"message":"Forbidden: Unable to process request","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+				},
+			},
+			e: &dutil.Err{
+				Status: 403,
+				Errors: map[string][]string{
+					"permission": {"Please ensure you have permission"},
+				},
+			},
+		},
+		{
+			name: "item deleted",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"item deleted","data":{},"errors":{}}`,
+				},
+			},
+			e: nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			e := s.DeleteItem(milk.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			q := tc.exchange.Request.URL.Query().Get("uuid")
+			if q != milk.UUID.String() {
+				t.Errorf("expected query uuid %s got %s", milk.UUID.String(), q)
+			}
+		})
+	}
+}
+
+func TestService_AddItemTag(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		EItem    Item
+		e        dutil.Error
+	}{
+		{
+			name: "tag not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"tag":["not found"]}}`,
+				},
+			},
+			EItem: Item{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"tag": {"not found"},
+				},
+			},
+		},
+		{
+			name: "tag added",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"item tag added","data":{"item":` + milkJSON + `},"errors":{}}`,
+				},
+			},
+			EItem: milk,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			item, e := s.AddItemTag(milk.UUID, groceries.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualItem(tc.EItem, item) {
+				t.Errorf("expected item %v got %v", tc.EItem, item)
+			}
+			if tc.exchange.Request.Method != "POST" {
+				t.Errorf("expected method %s got %s", "POST", tc.exchange.Request.Method)
+			}
+		})
+	}
+}
+
+func TestService_RemoveItemTag(t *testing.T) {
+	untagged := milk
+	untagged.Tags = Tags{}
+
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		EItem    Item
+		e        dutil.Error
+	}{
+		{
+			name: "item not tagged",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"tag":["item is not tagged with tag"]}}`,
+				},
+			},
+			EItem: Item{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"tag": {"item is not tagged with tag"},
+				},
+			},
+		},
+		{
+			name: "tag removed",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"item tag removed","data":{"item":{"uuid":"a03d4ac5-1d5b-465c-9e0a-c7658912c47d","transaction_uuid":"d441f6ba-4e40-477a-aa46-916b2dc56bb5","description":"milk","sku":1,"amount":24.99,"discount":2.50,"tags":[],"active":true,"create_date":"2022-06-20T08:00:00Z","update_date":"2022-06-20T08:00:00Z"}},"errors":{}}`,
+				},
+			},
+			EItem: untagged,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			item, e := s.RemoveItemTag(milk.UUID, groceries.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualItem(tc.EItem, item) {
+				t.Errorf("expected item %v got %v", tc.EItem, item)
+			}
+			q := tc.exchange.Request.URL.Query()
+			if q.Get("item_uuid") != milk.UUID.String() || q.Get("tag_uuid") != groceries.UUID.String() {
+				t.Errorf("expected query item_uuid %s and tag_uuid %s got %v", milk.UUID, groceries.UUID, q)
+			}
+		})
+	}
+}
+
+func TestService_GetItem(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		EItem    Item
+		status   int
+		e        dutil.Error
+	}{
+		{
+			name: "item not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"item":["not found"]}}`,
+				},
+			},
+			EItem:  Item{},
+			status: 404,
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"item": {"not found"},
+				},
+			},
+		},
+		{
+			name: "no item in response",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"item found","data":{},"errors":{}}`,
+				},
+			},
+			EItem:  Item{},
+			status: 404,
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"item": {"not found"},
+				},
+			},
+		},
+		{
+			name: "item found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"item found","data":{"item":` + milkJSON + `},"errors":{}}`,
+				},
+			},
+			EItem: milk,
+			e:     nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			item, e := s.GetItem(milk.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && dutil.Inst(e).Status != tc.status {
+				t.Errorf("expected status %d got %d", tc.status, dutil.Inst(e).Status)
+			}
+			if !EqualItem(tc.EItem, item) {
+				t.Errorf("expected item %v got %v", tc.EItem, item)
+			}
+		})
+	}
+}
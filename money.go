@@ -0,0 +1,294 @@
+package bankserv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dottics/dutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the ISO 4217 currency code of a Money value which has no
+// currency set.
+const DefaultCurrency string = "ZAR"
+
+// currencyExponents holds the number of minor unit digits for the currencies
+// that do not have the usual two digits, such as the Japanese yen which has no
+// minor unit.
+var currencyExponents = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"UGX": 0,
+	"VND": 0,
+}
+
+// Money is an exact monetary amount. The amount is stored as an integer number
+// of minor units (cents for ZAR) with the ISO 4217 currency code of the amount,
+// therefore, adding and subtracting amounts never drifts the way float
+// amounts do.
+//
+// A Money value without a Currency is in the DefaultCurrency. Money is
+// marshalled to a JSON number with exactly the number of decimals of the
+// currency, and can be unmarshalled from a JSON number or string.
+type Money struct {
+	MinorUnits int64
+	Currency   string
+}
+
+// NewMoney creates a Money value from an amount in minor units and a currency
+// code.
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{
+		MinorUnits: minorUnits,
+		Currency:   strings.ToUpper(currency),
+	}
+}
+
+// ParseMoney parses a decimal string such as "-236.19" to Money in the
+// currency passed to the function. The value is parsed digit by digit and
+// never passes through a float. Values with more decimals than the currency
+// has minor unit digits are rounded half away from zero.
+func ParseMoney(s string, currency string) (Money, dutil.Error) {
+	m := NewMoney(0, currency)
+	n, ok, inRange := parseDecimal(s, m.exponent())
+	if !ok {
+		return Money{}, newError(dutil.NewErr(400, "money", []string{fmt.Sprintf("invalid amount '%s'", s)}))
+	}
+	if !inRange {
+		return Money{}, newError(dutil.NewErr(400, "money", []string{fmt.Sprintf("amount '%s' out of range", s)}))
+	}
+	m.MinorUnits = n
+	return m, nil
+}
+
+// parseDecimal parses a decimal string such as "-236.19" to an integer with
+// exp decimal digits, for example 23619 for two digits, rounded half away from
+// zero. It reports whether the string is a decimal number and whether the
+// number is in the range of an int64.
+func parseDecimal(s string, exp int) (int64, bool, bool) {
+	v := strings.TrimSpace(s)
+	if v == "" {
+		return 0, false, false
+	}
+
+	negative := false
+	switch v[0] {
+	case '-':
+		negative = true
+		v = v[1:]
+	case '+':
+		v = v[1:]
+	}
+
+	whole, fraction := v, ""
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		whole, fraction = v[:i], v[i+1:]
+	}
+	if whole == "" && fraction == "" {
+		return 0, false, false
+	}
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return 0, false, false
+		}
+	}
+
+	// the digit after the last decimal digit decides the rounding
+	roundUp := false
+	if len(fraction) > exp {
+		roundUp = fraction[exp] >= '5'
+		fraction = fraction[:exp]
+	}
+	fraction += strings.Repeat("0", exp-len(fraction))
+
+	digits := strings.TrimLeft(whole+fraction, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	// rounding up the largest number overflows, for both a positive and a
+	// negative number as the sign is applied after the rounding
+	if err != nil || (roundUp && n == math.MaxInt64) {
+		return 0, true, false
+	}
+	if roundUp {
+		n++
+	}
+	if negative {
+		n = -n
+	}
+	return n, true, true
+}
+
+// MoneyFromFloat converts a float amount to Money in the currency passed to
+// the function. It exists for compatibility with float payloads, the shortest
+// decimal representation of the float is rounded to the nearest minor unit.
+// An amount which is out of the range of Money, or which is not a number,
+// returns an error.
+func MoneyFromFloat(f float64, currency string) (Money, dutil.Error) {
+	return ParseMoney(strconv.FormatFloat(f, 'f', -1, 64), currency)
+}
+
+// currency returns the currency code of the money in upper case, which is
+// the DefaultCurrency if no currency is set.
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(m.Currency)
+}
+
+// exponent returns the number of minor unit digits of the money's currency.
+func (m Money) exponent() int {
+	if exp, ok := currencyExponents[m.currency()]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Decimal returns the amount as a decimal string with exactly the number of
+// decimals of the currency, for example "-236.19".
+func (m Money) Decimal() string {
+	return formatDecimal(m.MinorUnits, m.exponent())
+}
+
+// formatDecimal returns the integer n with exp decimal digits as a decimal
+// string, for example "-236.19" for -23619 with two digits.
+func formatDecimal(n int64, exp int) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+	}
+	// use the unsigned value so that the smallest int64 does not overflow
+	u := uint64(n)
+	if n < 0 {
+		u = uint64(-(n + 1)) + 1
+	}
+	digits := strconv.FormatUint(u, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	i := len(digits) - exp
+	return sign + digits[:i] + "." + digits[i:]
+}
+
+// String returns the currency code and the decimal amount, for example
+// "ZAR 236.19".
+func (m Money) String() string {
+	return m.currency() + " " + m.Decimal()
+}
+
+// Float64 returns the amount as a float. The float is only an approximation
+// of the amount and should only be used for display purposes.
+func (m Money) Float64() float64 {
+	f, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return f
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.MinorUnits == 0
+}
+
+// Sign returns -1 if the amount is negative, 0 if the amount is zero and +1
+// if the amount is positive.
+func (m Money) Sign() int {
+	switch {
+	case m.MinorUnits < 0:
+		return -1
+	case m.MinorUnits > 0:
+		return 1
+	}
+	return 0
+}
+
+// Neg returns the amount with the sign reversed.
+func (m Money) Neg() Money {
+	m.MinorUnits = -m.MinorUnits
+	return m
+}
+
+// Equal reports whether a and m are the same amount in the same currency,
+// currency codes are compared regardless of case.
+func (m Money) Equal(a Money) bool {
+	return m.MinorUnits == a.MinorUnits && m.currency() == a.currency()
+}
+
+// Add returns the sum of m and a. Amounts in different currencies cannot be
+// added and an error is returned.
+func (m Money) Add(a Money) (Money, dutil.Error) {
+	if m.currency() != a.currency() {
+		return Money{}, currencyMismatch(m, a)
+	}
+	m.MinorUnits += a.MinorUnits
+	m.Currency = strings.ToUpper(m.Currency)
+	return m, nil
+}
+
+// Sub returns the difference of m and a. Amounts in different currencies
+// cannot be subtracted and an error is returned.
+func (m Money) Sub(a Money) (Money, dutil.Error) {
+	return m.Add(a.Neg())
+}
+
+// currencyMismatch returns the error for an operation on amounts with
+// different currencies.
+func currencyMismatch(a, b Money) dutil.Error {
+	return dutil.NewErr(400, "currency", []string{
+		fmt.Sprintf("currency mismatch %s and %s", a.currency(), b.currency()),
+	})
+}
+
+// MarshalJSON marshals the money to a JSON number with exactly the number of
+// decimals of the currency.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON unmarshals a JSON number, a JSON string containing a decimal
+// number or null to Money. The currency of the receiver is kept, because the
+// bank-service only exchanges the amount.
+func (m *Money) UnmarshalJSON(xb []byte) error {
+	xb = bytes.TrimSpace(xb)
+	if bytes.Equal(xb, []byte("null")) {
+		m.MinorUnits = 0
+		return nil
+	}
+	v := string(xb)
+	if len(xb) > 0 && xb[0] == '"' {
+		err := json.Unmarshal(xb, &v)
+		if err != nil {
+			return err
+		}
+	} else if strings.ContainsAny(v, "eE") {
+		// a number in exponent notation is only sent by float encoders
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		a, e := MoneyFromFloat(f, m.Currency)
+		if e != nil {
+			return e
+		}
+		*m = a
+		return nil
+	}
+	a, e := ParseMoney(v, m.Currency)
+	if e != nil {
+		return e
+	}
+	*m = a
+	return nil
+}
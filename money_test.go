@@ -0,0 +1,441 @@
+package bankserv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tt := []struct {
+		name     string
+		s        string
+		currency string
+		m        Money
+		e        dutil.Error
+	}{
+		{
+			name: "empty",
+			s:    "",
+			e:    dutil.NewErr(400, "money", []string{"invalid amount ''"}),
+		},
+		{
+			name: "not a number",
+			s:    "12a.00",
+			e:    dutil.NewErr(400, "money", []string{"invalid amount '12a.00'"}),
+		},
+		{
+			name: "only a point",
+			s:    ".",
+			e:    dutil.NewErr(400, "money", []string{"invalid amount '.'"}),
+		},
+		{
+			name: "out of range",
+			s:    "92233720368547758.08",
+			e:    dutil.NewErr(400, "money", []string{"amount '92233720368547758.08' out of range"}),
+		},
+		{
+			name: "out of range after rounding",
+			s:    "92233720368547758.075",
+			e:    dutil.NewErr(400, "money", []string{"amount '92233720368547758.075' out of range"}),
+		},
+		{
+			name: "negative out of range after rounding",
+			s:    "-92233720368547758.075",
+			e:    dutil.NewErr(400, "money", []string{"amount '-92233720368547758.075' out of range"}),
+		},
+		{
+			name: "largest amount",
+			s:    "-92233720368547758.07",
+			m:    Money{MinorUnits: -math.MaxInt64},
+		},
+		{
+			name: "whole amount",
+			s:    "236",
+			m:    Money{MinorUnits: 23600},
+		},
+		{
+			name: "cents",
+			s:    "236.19",
+			m:    Money{MinorUnits: 23619},
+		},
+		{
+			name: "single decimal",
+			s:    "37.6",
+			m:    Money{MinorUnits: 3760},
+		},
+		{
+			name: "no whole part",
+			s:    ".5",
+			m:    Money{MinorUnits: 50},
+		},
+		{
+			name: "negative",
+			s:    "-0.05",
+			m:    Money{MinorUnits: -5},
+		},
+		{
+			name: "positive sign",
+			s:    "+10.00",
+			m:    Money{MinorUnits: 1000},
+		},
+		{
+			name: "round half up",
+			s:    "236.185",
+			m:    Money{MinorUnits: 23619},
+		},
+		{
+			name: "round down",
+			s:    "236.1849",
+			m:    Money{MinorUnits: 23618},
+		},
+		{
+			name: "round half away from zero",
+			s:    "-236.185",
+			m:    Money{MinorUnits: -23619},
+		},
+		{
+			name:     "currency without minor units",
+			s:        "1500",
+			currency: "jpy",
+			m:        Money{MinorUnits: 1500, Currency: "JPY"},
+		},
+		{
+			name:     "currency with three minor unit digits",
+			s:        "1.5",
+			currency: "BHD",
+			m:        Money{MinorUnits: 1500, Currency: "BHD"},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			m, e := ParseMoney(tc.s, tc.currency)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && !errors.Is(e, ErrValidation) {
+				t.Errorf("expected a validation error got %v", e)
+			}
+			if m != tc.m {
+				t.Errorf("expected money %v got %v", tc.m, m)
+			}
+		})
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tt := []struct {
+		name string
+		f    float64
+		m    Money
+		e    dutil.Error
+	}{
+		{
+			name: "float32 amount",
+			f:    float64(float32(236.19)),
+			m:    Money{MinorUnits: 23619},
+		},
+		{
+			name: "float64 amount",
+			f:    0.1 + 0.2,
+			m:    Money{MinorUnits: 30},
+		},
+		{
+			name: "negative amount",
+			f:    -37.6,
+			m:    Money{MinorUnits: -3760},
+		},
+		{
+			name: "out of range",
+			f:    1e30,
+			e:    dutil.NewErr(400, "money", []string{"amount '1000000000000000000000000000000' out of range"}),
+		},
+		{
+			name: "not a number",
+			f:    math.NaN(),
+			e:    dutil.NewErr(400, "money", []string{"invalid amount 'NaN'"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			m, e := MoneyFromFloat(tc.f, "")
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if m != tc.m {
+				t.Errorf("expected money %v got %v", tc.m, m)
+			}
+		})
+	}
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	tt := []struct {
+		name string
+		m    Money
+		s    string
+	}{
+		{
+			name: "zero",
+			m:    Money{},
+			s:    "0.00",
+		},
+		{
+			name: "cents",
+			m:    Money{MinorUnits: 5},
+			s:    "0.05",
+		},
+		{
+			name: "negative",
+			m:    Money{MinorUnits: -23619},
+			s:    "-236.19",
+		},
+		{
+			name: "no minor units",
+			m:    Money{MinorUnits: 1500, Currency: "JPY"},
+			s:    "1500",
+		},
+		{
+			name: "three minor unit digits",
+			m:    Money{MinorUnits: 1, Currency: "KWD"},
+			s:    "0.001",
+		},
+		{
+			name: "smallest amount",
+			m:    Money{MinorUnits: -9223372036854775808},
+			s:    "-92233720368547758.08",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := tc.m.Decimal()
+			if s != tc.s {
+				t.Errorf("expected decimal %s got %s", tc.s, s)
+			}
+		})
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	m := Money{MinorUnits: 23619}
+	if m.String() != "ZAR 236.19" {
+		t.Errorf("expected %s got %s", "ZAR 236.19", m.String())
+	}
+}
+
+func TestMoney_Add(t *testing.T) {
+	tt := []struct {
+		name string
+		a    Money
+		b    Money
+		m    Money
+		e    dutil.Error
+	}{
+		{
+			name: "same currency",
+			a:    Money{MinorUnits: 23619},
+			b:    Money{MinorUnits: -3760},
+			m:    Money{MinorUnits: 19859},
+		},
+		{
+			name: "default currency",
+			a:    Money{MinorUnits: 100},
+			b:    Money{MinorUnits: 100, Currency: "ZAR"},
+			m:    Money{MinorUnits: 200},
+		},
+		{
+			name: "currency in lower case",
+			a:    Money{MinorUnits: 100, Currency: "usd"},
+			b:    NewMoney(100, "USD"),
+			m:    Money{MinorUnits: 200, Currency: "USD"},
+		},
+		{
+			name: "currency mismatch",
+			a:    Money{MinorUnits: 100},
+			b:    Money{MinorUnits: 100, Currency: "USD"},
+			m:    Money{},
+			e:    dutil.NewErr(400, "currency", []string{"currency mismatch ZAR and USD"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			m, e := tc.a.Add(tc.b)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if m != tc.m {
+				t.Errorf("expected money %v got %v", tc.m, m)
+			}
+		})
+	}
+}
+
+func TestMoney_Equal(t *testing.T) {
+	tt := []struct {
+		name  string
+		a     Money
+		b     Money
+		equal bool
+	}{
+		{"same amount", NewMoney(23619, "ZAR"), NewMoney(23619, "ZAR"), true},
+		{"default currency", Money{MinorUnits: 23619}, NewMoney(23619, "ZAR"), true},
+		{"currency in lower case", Money{MinorUnits: 23619, Currency: "zar"}, NewMoney(23619, "ZAR"), true},
+		{"different amount", NewMoney(23619, "ZAR"), NewMoney(23618, "ZAR"), false},
+		{"different currency", NewMoney(23619, "ZAR"), NewMoney(23619, "USD"), false},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			if tc.a.Equal(tc.b) != tc.equal || tc.b.Equal(tc.a) != tc.equal {
+				t.Errorf("expected %v equal to %v to be %v", tc.a, tc.b, tc.equal)
+			}
+		})
+	}
+}
+
+func TestMoney_Sub(t *testing.T) {
+	m, e := Money{MinorUnits: 3760}.Sub(Money{MinorUnits: 345})
+	if e != nil {
+		t.Errorf("unexpected error %v", e)
+	}
+	if m != (Money{MinorUnits: 3415}) {
+		t.Errorf("expected money %v got %v", Money{MinorUnits: 3415}, m)
+	}
+}
+
+// TestMoney_sum checks that summing many amounts does not drift the way
+// float amounts do.
+func TestMoney_sum(t *testing.T) {
+	sum := Money{}
+	for i := 0; i < 10000; i++ {
+		m, e := ParseMoney("0.10", "")
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		sum, e = sum.Add(m)
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+	}
+	if sum.Decimal() != "1000.00" {
+		t.Errorf("expected sum %s got %s", "1000.00", sum.Decimal())
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	tt := []struct {
+		name string
+		json string
+		m    Money
+		out  string
+		err  bool
+	}{
+		{
+			name: "number",
+			json: `236.19`,
+			m:    Money{MinorUnits: 23619},
+			out:  `236.19`,
+		},
+		{
+			name: "integer",
+			json: `0`,
+			m:    Money{},
+			out:  `0.00`,
+		},
+		{
+			name: "string",
+			json: `"-236.19"`,
+			m:    Money{MinorUnits: -23619},
+			out:  `-236.19`,
+		},
+		{
+			name: "float payload",
+			json: `236.19000244140625`,
+			m:    Money{MinorUnits: 23619},
+			out:  `236.19`,
+		},
+		{
+			name: "exponent notation",
+			json: `2.3619e2`,
+			m:    Money{MinorUnits: 23619},
+			out:  `236.19`,
+		},
+		{
+			name: "null",
+			json: `null`,
+			m:    Money{},
+			out:  `0.00`,
+		},
+		{
+			name: "invalid string",
+			json: `"R 236.19"`,
+			err:  true,
+		},
+		{
+			name: "out of range exponent notation",
+			json: `1e30`,
+			err:  true,
+		},
+		{
+			name: "out of range number",
+			json: `100000000000000000000`,
+			err:  true,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			m := Money{}
+			err := json.Unmarshal([]byte(tc.json), &m)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if m != tc.m {
+				t.Errorf("expected money %v got %v", tc.m, m)
+			}
+			xb, err := json.Marshal(m)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if string(xb) != tc.out {
+				t.Errorf("expected json %s got %s", tc.out, string(xb))
+			}
+		})
+	}
+}
+
+func TestItem_JSON(t *testing.T) {
+	i := Item{}
+	err := json.Unmarshal([]byte(`{"description":"two","sku":1,"amount":236.19,"discount":"3.45"}`), &i)
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !i.Amount.Equal(Money{MinorUnits: 23619}) {
+		t.Errorf("expected amount %v got %v", Money{MinorUnits: 23619}, i.Amount)
+	}
+	if !i.Discount.Equal(Money{MinorUnits: 345}) {
+		t.Errorf("expected discount %v got %v", Money{MinorUnits: 345}, i.Discount)
+	}
+	if i.SKU != Units(1) {
+		t.Errorf("expected sku %v got %v", Units(1), i.SKU)
+	}
+}
@@ -0,0 +1,347 @@
+package bankserv
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dottics/dutil"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BalanceError is the error of a statement of which the opening balance
+// plus the movements of the transactions does not equal the closing balance,
+// which means the statement is incomplete or transactions were read wrong.
+// A BalanceError is of the kind ErrValidation with the key "balance".
+//
+// Use errors.As to access the balances:
+//
+//	var be *BalanceError
+//	if errors.As(e, &be) {
+//		log.Println(be.Reference, be.Difference)
+//	}
+type BalanceError struct {
+	*dutil.Err
+	// Reference is the reference of the statement
+	Reference string
+	Opening   Money
+	// Movements is the sum of the amounts of the transactions
+	Movements Money
+	Closing   Money
+	// Difference is the closing balance minus the opening balance plus the
+	// movements
+	Difference Money
+}
+
+// Is reports whether the kind of the error is target, a BalanceError is of
+// the kind ErrValidation.
+func (e *BalanceError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// mt940Line is the pattern of the first line of a :61: statement line: the
+// value date, the optional entry date, the debit or credit mark, the
+// optional funds code, the amount, the transaction type, the reference of
+// the account owner and the optional reference of the bank.
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([A-Z][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+
+// mt940Balance is the pattern of a balance: the debit or credit mark, the
+// date, the currency and the amount.
+var mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)$`)
+
+// mt940Field is a field of an MT940 message, the tag and the lines of the
+// value of the field.
+type mt940Field struct {
+	line  int
+	tag   string
+	value string
+}
+
+// ParseMT940 reads the statements of an MT940 file, a customer statement
+// message of SWIFT, which may have several statements. Each statement starts
+// with the tag :20:, the reference of the statement, and has the tags:
+//   - :25: the account of the statement, the BIC or bank code and the
+//     account number separated by a slash, or the IBAN,
+//   - :28C: the number of the statement,
+//   - :60F: or :60M: the opening balance,
+//   - :61: a statement line, which is a transaction, followed by an optional
+//     :86: with the information of the transaction for the account owner,
+//   - :62F: or :62M: the closing balance and :64: the available balance.
+//
+// The entry date of a statement line is the Date of the transaction and the
+// value date its ValueDate, the :86: is the description and the reference of
+// the bank, or else the reference of the account owner, is the ExternalID.
+// The account of :25: is matched with the IBAN or the AccountNumber of the
+// bank accounts passed to the function to set the AccountUUID of the
+// statement and its transactions.
+//
+// The fields which are not able to be parsed are reported in an error of the
+// kind ErrValidation keyed by the line of the field, for example "line 12".
+// If every field is parsed, but the opening balance plus the movements of a
+// statement does not equal its closing balance, a *BalanceError of the first
+// statement which does not balance is returned. The statements are returned
+// with either error.
+func ParseMT940(r io.Reader, xba BankAccounts) ([]Statement, dutil.Error) {
+	xb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, dutil.NewErr(500, "read", []string{err.Error()})
+	}
+	fields := mt940Fields(Latin1.decode(xb))
+
+	v := validation{}
+	xs := []Statement{}
+	var st *Statement
+	// the transaction which the :86: belongs to
+	var last *Transaction
+	for _, f := range fields {
+		key := fmt.Sprintf("line %d", f.line)
+		if f.tag == "20" {
+			xs = append(xs, Statement{Reference: f.value, Transactions: Transactions{}})
+			st = &xs[len(xs)-1]
+			last = nil
+			continue
+		}
+		if st == nil {
+			v.add(key, fmt.Sprintf("field :%s: before the :20: of a statement", f.tag))
+			continue
+		}
+		switch f.tag {
+		case "25":
+			account := strings.TrimSpace(f.value)
+			if i := strings.LastIndexByte(account, '/'); i >= 0 {
+				account = account[i+1:]
+			}
+			st.AccountNumber = account
+			if iban, e := ParseIBAN(account); e == nil {
+				st.IBAN = iban
+			}
+		case "28C", "28":
+			st.Number = f.value
+		case "60F", "60M", "62F", "62M", "64":
+			b, currency, reason := mt940ParseBalance(f.value)
+			if reason != "" {
+				v.add(key, reason)
+				continue
+			}
+			st.Currency = currency
+			switch f.tag[:2] {
+			case "60":
+				st.Opening = b
+			case "62":
+				st.Closing = b
+			default:
+				st.Available = b
+			}
+		case "61":
+			t, reason := mt940Transaction(f.value, st.Currency)
+			if reason != "" {
+				v.add(key, reason)
+				last = nil
+				continue
+			}
+			st.Transactions = append(st.Transactions, t)
+			last = &st.Transactions[len(st.Transactions)-1]
+		case "86":
+			if last == nil {
+				continue
+			}
+			info := strings.Join(strings.Fields(f.value), " ")
+			last.Description = info
+			last.Items[0].Description = info
+			last = nil
+		}
+	}
+	for i := range xs {
+		xs[i].matchBankAccount(xba)
+	}
+	if e := v.err(); e != nil {
+		return xs, e
+	}
+	for _, st := range xs {
+		if e := st.checkBalance(); e != nil {
+			return xs, e
+		}
+	}
+	return xs, nil
+}
+
+// mt940Fields splits the text of an MT940 file into its fields. The lines of
+// the SWIFT blocks around a message, such as {1:...}{2:...}{4: and -}, and
+// the end of a message, -, are not part of a field.
+func mt940Fields(s string) []mt940Field {
+	var xf []mt940Field
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), "\r ")
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "", trimmed == "-", strings.HasPrefix(trimmed, "{"), strings.HasPrefix(trimmed, "-}"):
+			continue
+		case strings.HasPrefix(text, ":"):
+			if i := strings.IndexByte(text[1:], ':'); i > 0 {
+				xf = append(xf, mt940Field{
+					line:  line,
+					tag:   text[1 : i+1],
+					value: text[i+2:],
+				})
+				continue
+			}
+		}
+		if len(xf) > 0 {
+			// a line of a field with more than one line
+			xf[len(xf)-1].value += "\n" + text
+		}
+	}
+	return xf
+}
+
+// mt940ParseBalance parses a balance field, such as C220601ZAR1234,56, and
+// returns the balance and its currency, or the reason it is not able to be
+// parsed.
+func mt940ParseBalance(s string) (*StatementBalance, string, string) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, "", fmt.Sprintf("invalid balance '%s'", s)
+	}
+	date, ok := mt940Date(m[2])
+	if !ok {
+		return nil, "", fmt.Sprintf("invalid balance date '%s'", m[2])
+	}
+	amount, ok := mt940Money(m[4], m[3])
+	if !ok {
+		return nil, "", fmt.Sprintf("invalid balance amount '%s'", m[4])
+	}
+	if m[1] == "D" {
+		amount = amount.Neg()
+	}
+	return &StatementBalance{Amount: amount, Date: date}, m[3], ""
+}
+
+// mt940Transaction returns the transaction of a :61: statement line, or the
+// reason it is not able to be parsed. The description of the transaction is
+// the supplementary details on the second line of the statement line, if
+// any, until it is replaced by the :86: of the transaction.
+func mt940Transaction(s string, currency string) (Transaction, string) {
+	lines := strings.SplitN(s, "\n", 2)
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if m == nil {
+		return Transaction{}, fmt.Sprintf("invalid statement line '%s'", lines[0])
+	}
+	valueDate, ok := mt940Date(m[1])
+	if !ok {
+		return Transaction{}, fmt.Sprintf("invalid value date '%s'", m[1])
+	}
+	date := valueDate
+	if m[2] != "" {
+		entry, err := time.Parse("0102", m[2])
+		if err != nil {
+			return Transaction{}, fmt.Sprintf("invalid entry date '%s'", m[2])
+		}
+		// the entry date is in the year of the value date, unless the dates
+		// are on either side of the end of a year
+		y := valueDate.Year()
+		switch {
+		case entry.Month() == time.December && valueDate.Month() == time.January:
+			y--
+		case entry.Month() == time.January && valueDate.Month() == time.December:
+			y++
+		}
+		date = time.Date(y, entry.Month(), entry.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	amount, ok := mt940Money(m[5], currency)
+	if !ok {
+		return Transaction{}, fmt.Sprintf("invalid amount '%s'", m[5])
+	}
+	// a debit and the reversal of a credit decrease the balance
+	if m[3] == "D" || m[3] == "RC" {
+		amount = amount.Neg()
+	}
+
+	reference := strings.TrimSpace(m[7])
+	externalID := strings.TrimSpace(m[8])
+	if externalID == "" && reference != "NONREF" {
+		externalID = reference
+	}
+	description := reference
+	if len(lines) == 2 && strings.TrimSpace(lines[1]) != "" {
+		description = strings.TrimSpace(lines[1])
+	}
+	return Transaction{
+		ExternalID:  externalID,
+		Date:        date,
+		ValueDate:   &valueDate,
+		Description: description,
+		Items: Items{
+			{
+				Description: description,
+				SKU:         Units(1),
+				Amount:      amount,
+				Active:      true,
+			},
+		},
+		Active: true,
+	}, ""
+}
+
+// mt940Date parses a date of an MT940 file, YYMMDD. The years are in the
+// 2000s, apart from 80 to 99 which are in the 1900s.
+func mt940Date(s string) (time.Time, bool) {
+	t, err := time.Parse("060102", s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() >= 2080 {
+		t = t.AddDate(-100, 0, 0)
+	}
+	return t, true
+}
+
+// mt940Money parses an amount of an MT940 file, which has a comma as the
+// decimal separator and may end with the comma, such as 1234, or 1234,5.
+func mt940Money(s string, currency string) (Money, bool) {
+	m, e := ParseMoney(strings.Replace(s, ",", ".", 1), currency)
+	return m, e == nil
+}
+
+// checkBalance returns a *BalanceError if the opening balance plus the
+// movements of the transactions of the statement does not equal the closing
+// balance. A statement without an opening or closing balance is not checked.
+func (st Statement) checkBalance() dutil.Error {
+	if st.Opening == nil || st.Closing == nil {
+		return nil
+	}
+	movements := NewMoney(0, st.Currency)
+	for _, t := range st.Transactions {
+		net, e := t.Net()
+		if e != nil {
+			return e
+		}
+		movements, e = addNet(movements, net)
+		if e != nil {
+			return e
+		}
+	}
+	expected, e := st.Opening.Amount.Add(movements)
+	if e != nil {
+		return e
+	}
+	if expected.Equal(st.Closing.Amount) {
+		return nil
+	}
+	difference, e := st.Closing.Amount.Sub(expected)
+	if e != nil {
+		return e
+	}
+	msg := fmt.Sprintf("opening balance %s plus movements %s is %s, which does not equal the closing balance %s",
+		st.Opening.Amount.Decimal(), movements.Decimal(), expected.Decimal(), st.Closing.Amount.Decimal())
+	return &BalanceError{
+		Err:        dutil.NewErr(400, "balance", []string{msg}),
+		Reference:  st.Reference,
+		Opening:    st.Opening.Amount,
+		Movements:  movements,
+		Closing:    st.Closing.Amount,
+		Difference: difference,
+	}
+}
@@ -0,0 +1,156 @@
+package bankserv
+
+import (
+	"errors"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+)
+
+const mt940Statement = `{1:F01NEDSZAJJAXXX0000000000}{2:O9401200220701NEDSZAJJAXXX00000000002207011200N}{4:
+:20:STMT220630
+:25:NEDSZAJJ/1234567890
+:28C:00006/001
+:60F:C220601ZAR10000,00
+:61:2206180618D236,19NMSCNONREF//FT22169ABC
+SUPERSPAR
+:86:SUPERSPAR JEFFREYS BAY
+EASTERN CAPE ZA
+:61:2206250625C15000,NTRFSALARY JUNE
+:61:2206300701D5,00NCHGNONREF
+:86:MONTHLY FEE
+:62F:C220630ZAR24758,81
+:64:C220630ZAR24700,00
+-}
+{1:F01NEDSZAJJAXXX0000000000}{2:O9401200220701NEDSZAJJAXXX00000000002207011200N}{4:
+:20:STMT220630B
+:25:GB82WEST12345698765432
+:28C:00001/001
+:60F:D220601GBP100,
+:61:220615C100,50NTRF123//456
+:62F:C220630GBP0,50
+-}
+`
+
+func TestParseMT940(t *testing.T) {
+	organisation := organisationBankAccount
+	organisation.AccountNumber = "1234567890"
+	international := BankAccount{
+		UUID: uuid.MustParse("7a4c51ab-c87b-4fd3-8d84-b5d2e3e7b0b1"),
+		IBAN: "GB82WEST12345698765432",
+	}
+
+	xs, e := ParseMT940(strings.NewReader(mt940Statement), BankAccounts{international, organisation})
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if len(xs) != 2 {
+		t.Fatalf("expected 2 statements got %d", len(xs))
+	}
+
+	st := xs[0]
+	if st.Reference != "STMT220630" || st.Number != "00006/001" || st.AccountNumber != "1234567890" || st.AccountUUID != organisation.UUID {
+		t.Errorf("expected the statement of %s got %v", organisation.UUID, st)
+	}
+	if st.Opening == nil || st.Opening.Amount != NewMoney(1000000, "ZAR") || !st.Opening.Date.Equal(timeMustParse("2022-06-01T00:00:00Z")) {
+		t.Errorf("expected opening balance 10000.00 got %v", st.Opening)
+	}
+	if st.Closing == nil || st.Closing.Amount != NewMoney(2475881, "ZAR") {
+		t.Errorf("expected closing balance 24758.81 got %v", st.Closing)
+	}
+	if st.Available == nil || st.Available.Amount != NewMoney(2470000, "ZAR") {
+		t.Errorf("expected available balance 24700.00 got %v", st.Available)
+	}
+	expected := Transactions{
+		{
+			AccountUUID: organisation.UUID,
+			ExternalID:  "FT22169ABC",
+			Date:        timeMustParse("2022-06-18T00:00:00Z"),
+			ValueDate:   timeMustParsePtr("2022-06-18T00:00:00Z"),
+			Description: "SUPERSPAR JEFFREYS BAY EASTERN CAPE ZA",
+			Items: Items{
+				{Description: "SUPERSPAR JEFFREYS BAY EASTERN CAPE ZA", SKU: Units(1), Amount: NewMoney(-23619, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+		{
+			AccountUUID: organisation.UUID,
+			ExternalID:  "SALARY JUNE",
+			Date:        timeMustParse("2022-06-25T00:00:00Z"),
+			ValueDate:   timeMustParsePtr("2022-06-25T00:00:00Z"),
+			Description: "SALARY JUNE",
+			Items: Items{
+				{Description: "SALARY JUNE", SKU: Units(1), Amount: NewMoney(1500000, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+		{
+			AccountUUID: organisation.UUID,
+			Date:        timeMustParse("2022-07-01T00:00:00Z"),
+			ValueDate:   timeMustParsePtr("2022-06-30T00:00:00Z"),
+			Description: "MONTHLY FEE",
+			Items: Items{
+				{Description: "MONTHLY FEE", SKU: Units(1), Amount: NewMoney(-500, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+	}
+	if !EqualTransactions(expected, st.Transactions) {
+		t.Errorf("expected %v got %v", expected, st.Transactions)
+	}
+
+	st = xs[1]
+	if st.IBAN != international.IBAN || st.AccountUUID != international.UUID || st.Currency != "GBP" {
+		t.Errorf("expected the statement of %s got %v", international.UUID, st)
+	}
+	if st.Opening.Amount != NewMoney(-10000, "GBP") || len(st.Transactions) != 1 || st.Transactions[0].ExternalID != "456" {
+		t.Errorf("expected a debit opening balance and a transaction got %v", st)
+	}
+}
+
+func TestParseMT940_balance(t *testing.T) {
+	statement := strings.Replace(mt940Statement, ":62F:C220630ZAR24758,81", ":62F:C220630ZAR24768,81", 1)
+	xs, e := ParseMT940(strings.NewReader(statement), nil)
+	if len(xs) != 2 {
+		t.Errorf("expected the statements with the error got %d", len(xs))
+	}
+	var be *BalanceError
+	if !errors.As(e, &be) {
+		t.Fatalf("expected a balance error got %v", e)
+	}
+	if !errors.Is(e, ErrValidation) || be.Status != 400 {
+		t.Errorf("expected a validation error with status 400 got %v", e)
+	}
+	if be.Reference != "STMT220630" || be.Opening != NewMoney(1000000, "ZAR") || be.Movements != NewMoney(1475881, "ZAR") ||
+		be.Closing != NewMoney(2476881, "ZAR") || be.Difference != NewMoney(1000, "ZAR") {
+		t.Errorf("unexpected balances %v", be)
+	}
+	te := dutil.NewErr(400, "balance", []string{"opening balance 10000.00 plus movements 14758.81 is 24758.81, which does not equal the closing balance 24768.81"})
+	if !dutil.ErrorEqual(te, e) {
+		t.Errorf("expected error %v got %v", te, e)
+	}
+}
+
+func TestParseMT940_invalid(t *testing.T) {
+	statement := ":25:1234567890\n" +
+		":20:STMT\n" +
+		":60F:C220601ZAR10000.00\n" +
+		":61:2206180618X236,19NMSCNONREF\n" +
+		":62F:C220630ZAR10000,00\n"
+	xs, e := ParseMT940(strings.NewReader(statement), nil)
+	te := &dutil.Err{
+		Status: 400,
+		Errors: map[string][]string{
+			"line 1": {"field :25: before the :20: of a statement"},
+			"line 3": {"invalid balance 'C220601ZAR10000.00'"},
+			"line 4": {"invalid statement line '2206180618X236,19NMSCNONREF'"},
+		},
+	}
+	if !dutil.ErrorEqual(te, e) || !errors.Is(e, ErrValidation) {
+		t.Errorf("expected error %v got %v", te, e)
+	}
+	if len(xs) != 1 || xs[0].Opening != nil || len(xs[0].Transactions) != 0 {
+		t.Errorf("expected a statement without the invalid fields got %v", xs)
+	}
+}
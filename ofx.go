@@ -0,0 +1,374 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ofxNode is an element of an OFX document. A leaf element has a value and
+// an aggregate element has children.
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+// child returns the first child of the node with the name, or nil if the
+// node does not have a child with the name.
+func (n *ofxNode) child(name string) *ofxNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// text returns the value of the descendant of the node at the path of names,
+// or an empty string if the node does not have the descendant.
+func (n *ofxNode) text(path ...string) string {
+	for _, name := range path {
+		n = n.child(name)
+	}
+	if n == nil {
+		return ""
+	}
+	return n.value
+}
+
+// find returns the descendants of the node with one of the names, in the
+// order of the document.
+func (n *ofxNode) find(names ...string) []*ofxNode {
+	if n == nil {
+		return nil
+	}
+	var xn []*ofxNode
+	for _, c := range n.children {
+		for _, name := range names {
+			if c.name == name {
+				xn = append(xn, c)
+				break
+			}
+		}
+		xn = append(xn, c.find(names...)...)
+	}
+	return xn
+}
+
+// ParseOFX reads the bank and credit card statements of an OFX or QFX file.
+// Both OFX 1.x, which is SGML with elements that are not closed, and OFX 2.x,
+// which is XML, are supported.
+//
+// The STMTTRN records are read into transactions with the FITID as the
+// ExternalID of the transaction, the NAME, or the NAME of the PAYEE, as the
+// description and a single item with the MEMO as its description. The
+// LEDGERBAL and AVAILBAL are the Closing and Available balances of the
+// statement. The ACCTID of a statement is matched with the AccountNumber of
+// the bank accounts passed to the function to set the AccountUUID of the
+// statement and its transactions.
+//
+// A file which is not an OFX file returns an error of the kind ErrValidation
+// with the key "ofx". The transactions which are not able to be parsed are
+// skipped and reported in an error keyed by the number of the transaction in
+// the file, for example "transaction 3", such that the statements are still
+// returned.
+func ParseOFX(r io.Reader, xba BankAccounts) ([]Statement, dutil.Error) {
+	xb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, dutil.NewErr(500, "read", []string{err.Error()})
+	}
+	root, reason := parseOFXTree(ofxEncoding(xb).decode(xb))
+	if reason != "" {
+		return nil, newError(dutil.NewErr(400, "ofx", []string{reason}))
+	}
+
+	v := validation{}
+	n := 0
+	xs := []Statement{}
+	for _, rs := range root.find("STMTRS", "CCSTMTRS") {
+		st := Statement{
+			Currency: strings.ToUpper(rs.text("CURDEF")),
+		}
+		if rs.name == "CCSTMTRS" {
+			st.AccountNumber = rs.text("CCACCTFROM", "ACCTID")
+		} else {
+			st.AccountNumber = rs.text("BANKACCTFROM", "ACCTID")
+		}
+		for _, b := range []struct {
+			name    string
+			balance **StatementBalance
+		}{
+			{"LEDGERBAL", &st.Closing},
+			{"AVAILBAL", &st.Available},
+		} {
+			node := rs.child(b.name)
+			if node == nil {
+				continue
+			}
+			amount, ok := ofxMoney(node.text("BALAMT"), st.Currency)
+			date, dok := ofxDate(node.text("DTASOF"))
+			if !ok || !dok {
+				v.add(strings.ToLower(b.name), fmt.Sprintf("invalid balance '%s' at '%s'", node.text("BALAMT"), node.text("DTASOF")))
+				continue
+			}
+			*b.balance = &StatementBalance{Amount: amount, Date: date}
+		}
+
+		st.Transactions = Transactions{}
+		for _, tn := range rs.child("BANKTRANLIST").find("STMTTRN") {
+			n++
+			t, reasons := ofxTransaction(tn, st.Currency)
+			if len(reasons) > 0 {
+				v.add(fmt.Sprintf("transaction %d", n), reasons...)
+				continue
+			}
+			st.Transactions = append(st.Transactions, t)
+		}
+		st.matchBankAccount(xba)
+		xs = append(xs, st)
+	}
+	return xs, v.err()
+}
+
+// ofxTransaction returns the transaction of a STMTTRN record, or the reasons
+// the record is not able to be parsed.
+func ofxTransaction(n *ofxNode, currency string) (Transaction, []string) {
+	var reasons []string
+	date, ok := ofxDate(n.text("DTPOSTED"))
+	if !ok {
+		reasons = append(reasons, fmt.Sprintf("invalid DTPOSTED '%s'", n.text("DTPOSTED")))
+	}
+	amount, ok := ofxMoney(n.text("TRNAMT"), currency)
+	if !ok {
+		reasons = append(reasons, fmt.Sprintf("invalid TRNAMT '%s'", n.text("TRNAMT")))
+	}
+	if len(reasons) > 0 {
+		return Transaction{}, reasons
+	}
+
+	name := n.text("NAME")
+	if name == "" {
+		name = n.text("PAYEE", "NAME")
+	}
+	memo := n.text("MEMO")
+	if name == "" {
+		name = memo
+	}
+	if memo == "" {
+		memo = name
+	}
+	return Transaction{
+		ExternalID:  n.text("FITID"),
+		Date:        date,
+		Description: name,
+		Items: Items{
+			{
+				Description: memo,
+				SKU:         Units(1),
+				Amount:      amount,
+				Active:      true,
+			},
+		},
+		Active: true,
+	}, nil
+}
+
+// parseOFXTree parses the elements of an OFX document from the OFX element,
+// or returns the reason the document is not able to be parsed. An element
+// with text is a leaf, regardless of whether the element is closed, which
+// makes the parser work for both the SGML and the XML documents. An element
+// without text is an aggregate if it is closed anywhere in the document,
+// otherwise it is an empty SGML leaf, such as a <NAME> without a name.
+func parseOFXTree(s string) (*ofxNode, string) {
+	start := strings.Index(strings.ToUpper(s), "<OFX>")
+	if start < 0 {
+		return nil, "not an OFX document"
+	}
+	closes := ofxClosedElements(s[start:])
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	i := start
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], '<')
+		if j < 0 {
+			j = len(s) - i
+		}
+		text := strings.TrimSpace(s[i : i+j])
+		top := stack[len(stack)-1]
+		if text != "" && len(stack) > 1 && len(top.children) == 0 && top.value == "" {
+			// the element is a leaf, an SGML leaf is not closed
+			top.value = ofxUnescape(text)
+			stack = stack[:len(stack)-1]
+		}
+		i += j
+		if i >= len(s) {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(s[i:], "<!--"):
+			k := strings.Index(s[i:], "-->")
+			if k < 0 {
+				return nil, "unterminated comment"
+			}
+			i += k + 3
+			continue
+		case strings.HasPrefix(s[i:], "<?"):
+			k := strings.Index(s[i:], "?>")
+			if k < 0 {
+				return nil, "unterminated processing instruction"
+			}
+			i += k + 2
+			continue
+		}
+		k := strings.IndexByte(s[i:], '>')
+		if k < 0 {
+			return nil, "unterminated element"
+		}
+		tag := strings.TrimSpace(s[i+1 : i+k])
+		i += k + 1
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			// an element which is already closed, such as an XML leaf, is
+			// not on the stack
+			for d := len(stack) - 1; d > 0; d-- {
+				if stack[d].name == name {
+					stack = stack[:d]
+					break
+				}
+			}
+			continue
+		}
+		closed := strings.HasSuffix(tag, "/")
+		tag = strings.TrimSuffix(tag, "/")
+		if f := strings.Fields(tag); len(f) > 0 {
+			tag = f[0]
+		}
+		if tag == "" {
+			return nil, "element without a name"
+		}
+		node := &ofxNode{name: strings.ToUpper(tag)}
+		top = stack[len(stack)-1]
+		if len(stack) > 1 && len(top.children) == 0 && top.value == "" && !closes[top.name] {
+			// the element is an empty SGML leaf, the element which starts is
+			// not its child
+			stack = stack[:len(stack)-1]
+			top = stack[len(stack)-1]
+		}
+		top.children = append(top.children, node)
+		if !closed {
+			stack = append(stack, node)
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, "not an OFX document"
+	}
+	return root, ""
+}
+
+// ofxClosedElements returns the names of the elements which have an end tag
+// in the document, which are the aggregates and the closed leaves.
+func ofxClosedElements(s string) map[string]bool {
+	closes := map[string]bool{}
+	for {
+		i := strings.Index(s, "</")
+		if i < 0 {
+			return closes
+		}
+		s = s[i+2:]
+		k := strings.IndexByte(s, '>')
+		if k < 0 {
+			return closes
+		}
+		closes[strings.ToUpper(strings.TrimSpace(s[:k]))] = true
+		s = s[k+1:]
+	}
+}
+
+// ofxUnescape replaces the character entities of the text of an OFX element.
+func ofxUnescape(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	return strings.NewReplacer(
+		"&lt;", "<",
+		"&gt;", ">",
+		"&quot;", `"`,
+		"&apos;", "'",
+		"&amp;", "&",
+	).Replace(s)
+}
+
+// ofxEncoding returns the encoding of an OFX document from the CHARSET of
+// the OFX 1.x header or the encoding of the XML declaration of OFX 2.x.
+func ofxEncoding(xb []byte) Encoding {
+	head := string(xb)
+	if i := strings.Index(strings.ToUpper(head), "<OFX>"); i >= 0 {
+		head = head[:i]
+	}
+	head = strings.ToUpper(head)
+	switch {
+	case strings.Contains(head, "CHARSET:1252"), strings.Contains(head, "WINDOWS-1252"):
+		return Windows1252
+	case strings.Contains(head, "ISO-8859-1"):
+		return Latin1
+	}
+	return UTF8
+}
+
+// ofxMoney parses an OFX amount, which may have a comma as the decimal
+// separator.
+func ofxMoney(s string, currency string) (Money, bool) {
+	v := strings.TrimSpace(s)
+	if !strings.Contains(v, ".") {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	m, e := ParseMoney(v, currency)
+	return m, e == nil
+}
+
+// ofxDate parses an OFX date, YYYYMMDDHHMMSS.XXX[gmt offset:tz name], of
+// which everything after the day is optional. A date without an offset is in
+// UTC.
+func ofxDate(s string) (time.Time, bool) {
+	v := strings.TrimSpace(s)
+	loc := time.UTC
+	if i := strings.IndexByte(v, '['); i >= 0 {
+		tz := strings.TrimSuffix(v[i+1:], "]")
+		v = v[:i]
+		if j := strings.IndexByte(tz, ':'); j >= 0 {
+			tz = tz[:j]
+		}
+		hours, err := strconv.ParseFloat(tz, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		v = v[:i]
+	}
+	layouts := map[int]string{
+		8:  "20060102",
+		12: "200601021504",
+		14: "20060102150405",
+	}
+	layout, ok := layouts[len(v)]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(layout, v, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
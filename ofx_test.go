@@ -0,0 +1,323 @@
+package bankserv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ofxSGML is an OFX 1.x statement with the Windows-1252 character set.
+const ofxSGML = "OFXHEADER:100\r\n" +
+	"DATA:OFXSGML\r\n" +
+	"VERSION:102\r\n" +
+	"SECURITY:NONE\r\n" +
+	"ENCODING:USASCII\r\n" +
+	"CHARSET:1252\r\n" +
+	"COMPRESSION:NONE\r\n" +
+	"OLDFILEUID:NONE\r\n" +
+	"NEWFILEUID:NONE\r\n" +
+	"\r\n" +
+	`<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20220630120000<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>ZAR
+<BANKACCTFROM>
+<BANKID>250655
+<ACCTID>6200-123-8911
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20220601
+<DTEND>20220630
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20220618152622.000[+2:SAST]
+<TRNAMT>-236.19
+<FITID>202206180001
+<NAME>SUPERSPAR JEFFREYS BAY
+<MEMO>Caf` + "\xe9" + ` &amp; groceries
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20220625
+<TRNAMT>15000,00
+<FITID>202206250001
+<NAME>SALARY
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>2022-06-26
+<TRNAMT>-10.00
+<FITID>202206260001
+<NAME>BROKEN
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>12763.81
+<DTASOF>20220630
+</LEDGERBAL>
+<AVAILBAL>
+<BALAMT>12500.00
+<DTASOF>20220630
+</AVAILBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+// ofxXML is an OFX 2.x statement with a bank and a credit card statement.
+const ofxXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <STMTRS>
+        <CURDEF>USD</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>121000248</BANKID>
+          <ACCTID>098765432109</ACCTID>
+          <ACCTTYPE>SAVINGS</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>INT</TRNTYPE>
+            <DTPOSTED>20220630000000.000[-5:EST]</DTPOSTED>
+            <TRNAMT>1.25</TRNAMT>
+            <FITID>INT-2022-06</FITID>
+            <PAYEE><NAME>Interest</NAME></PAYEE>
+            <MEMO></MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>2001.25</BALAMT>
+          <DTASOF>20220630</DTASOF>
+        </LEDGERBAL>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>USD</CURDEF>
+        <CCACCTFROM><ACCTID>4111111111111111</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20220612</DTPOSTED>
+            <TRNAMT>-42.00</TRNAMT>
+            <FITID>CC-1</FITID>
+            <NAME>Books &lt;online&gt;</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	cheque := BankAccount{
+		UUID:          uuid.MustParse("032203af-6002-4abc-9982-73c577add8df"),
+		AccountNumber: "62001238911",
+	}
+	savings := userBankAccount
+
+	t.Run("ofx 1.x", func(t *testing.T) {
+		xs, e := ParseOFX(strings.NewReader(ofxSGML), BankAccounts{savings, cheque})
+		te := dutil.NewErr(400, "transaction 3", []string{"invalid DTPOSTED '2022-06-26'"})
+		if !dutil.ErrorEqual(te, e) || !errors.Is(e, ErrValidation) {
+			t.Errorf("expected error %v got %v", te, e)
+		}
+		if len(xs) != 1 {
+			t.Fatalf("expected 1 statement got %d", len(xs))
+		}
+		st := xs[0]
+		if st.AccountNumber != "6200-123-8911" || st.AccountUUID != cheque.UUID || st.Currency != "ZAR" {
+			t.Errorf("expected the statement of %s got %s %s %s", cheque.UUID, st.AccountNumber, st.AccountUUID, st.Currency)
+		}
+		if st.Closing == nil || st.Closing.Amount != NewMoney(1276381, "ZAR") || !st.Closing.Date.Equal(timeMustParse("2022-06-30T00:00:00Z")) {
+			t.Errorf("expected closing balance 12763.81 got %v", st.Closing)
+		}
+		if st.Available == nil || st.Available.Amount != NewMoney(1250000, "ZAR") {
+			t.Errorf("expected available balance 12500.00 got %v", st.Available)
+		}
+
+		xt := Transactions{
+			{
+				AccountUUID: cheque.UUID,
+				ExternalID:  "202206180001",
+				Date:        timeMustParse("2022-06-18T13:26:22Z"),
+				Description: "SUPERSPAR JEFFREYS BAY",
+				Items: Items{
+					{Description: "Café & groceries", SKU: Units(1), Amount: NewMoney(-23619, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+			{
+				AccountUUID: cheque.UUID,
+				ExternalID:  "202206250001",
+				Date:        timeMustParse("2022-06-25T00:00:00Z"),
+				Description: "SALARY",
+				Items: Items{
+					{Description: "SALARY", SKU: Units(1), Amount: NewMoney(1500000, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+		}
+		assertOFXTransactions(t, xt, st.Transactions)
+	})
+
+	t.Run("ofx 2.x", func(t *testing.T) {
+		xs, e := ParseOFX(strings.NewReader(ofxXML), BankAccounts{savings, cheque})
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		if len(xs) != 2 {
+			t.Fatalf("expected 2 statements got %d", len(xs))
+		}
+		if xs[0].AccountUUID != savings.UUID || xs[0].Closing == nil || xs[0].Closing.Amount != NewMoney(200125, "USD") || xs[0].Available != nil {
+			t.Errorf("expected the savings statement with a closing balance got %v", xs[0])
+		}
+		assertOFXTransactions(t, Transactions{
+			{
+				AccountUUID: savings.UUID,
+				ExternalID:  "INT-2022-06",
+				Date:        timeMustParse("2022-06-30T05:00:00Z"),
+				Description: "Interest",
+				Items:       Items{{Description: "Interest", SKU: Units(1), Amount: NewMoney(125, "USD"), Active: true}},
+				Active:      true,
+			},
+		}, xs[0].Transactions)
+
+		if xs[1].AccountNumber != "4111111111111111" || xs[1].AccountUUID != uuid.Nil {
+			t.Errorf("expected an unmatched credit card statement got %s %s", xs[1].AccountNumber, xs[1].AccountUUID)
+		}
+		assertOFXTransactions(t, Transactions{
+			{
+				ExternalID:  "CC-1",
+				Date:        timeMustParse("2022-06-12T00:00:00Z"),
+				Description: "Books <online>",
+				Items:       Items{{Description: "Books <online>", SKU: Units(1), Amount: NewMoney(-4200, "USD"), Active: true}},
+				Active:      true,
+			},
+		}, xs[1].Transactions)
+	})
+
+	t.Run("empty sgml leaves", func(t *testing.T) {
+		doc := "<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>\n" +
+			"<CURDEF>ZAR\n" +
+			"<BANKACCTFROM><BANKID>250655<ACCTID>62001238911<ACCTTYPE>CHECKING</BANKACCTFROM>\n" +
+			"<BANKTRANLIST>\n" +
+			"<STMTTRN>\n" +
+			"<TRNTYPE>DEBIT\n" +
+			"<DTPOSTED>20220618\n" +
+			"<NAME>\n" +
+			"<MEMO>\n" +
+			"<TRNAMT>-10.00\n" +
+			"<FITID>202206180002\n" +
+			"</STMTTRN>\n" +
+			"<STMTTRN>\n" +
+			"<TRNTYPE>CREDIT\n" +
+			"<DTPOSTED>20220625\n" +
+			"<TRNAMT>15000.00\n" +
+			"<FITID>202206250001\n" +
+			"<NAME>SALARY\n" +
+			"<MEMO>\n" +
+			"</STMTTRN>\n" +
+			"</BANKTRANLIST>\n" +
+			"</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>\n"
+		xs, e := ParseOFX(strings.NewReader(doc), BankAccounts{cheque})
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		if len(xs) != 1 {
+			t.Fatalf("expected 1 statement got %d", len(xs))
+		}
+		if xs[0].AccountUUID != cheque.UUID {
+			t.Errorf("expected the statement of %s got %s", cheque.UUID, xs[0].AccountUUID)
+		}
+		assertOFXTransactions(t, Transactions{
+			{
+				AccountUUID: cheque.UUID,
+				ExternalID:  "202206180002",
+				Date:        timeMustParse("2022-06-18T00:00:00Z"),
+				Items:       Items{{SKU: Units(1), Amount: NewMoney(-1000, "ZAR"), Active: true}},
+				Active:      true,
+			},
+			{
+				AccountUUID: cheque.UUID,
+				ExternalID:  "202206250001",
+				Date:        timeMustParse("2022-06-25T00:00:00Z"),
+				Description: "SALARY",
+				Items:       Items{{Description: "SALARY", SKU: Units(1), Amount: NewMoney(1500000, "ZAR"), Active: true}},
+				Active:      true,
+			},
+		}, xs[0].Transactions)
+	})
+
+	t.Run("not ofx", func(t *testing.T) {
+		_, e := ParseOFX(strings.NewReader("Date,Description,Amount\n"), nil)
+		te := dutil.NewErr(400, "ofx", []string{"not an OFX document"})
+		if !dutil.ErrorEqual(te, e) || !errors.Is(e, ErrValidation) {
+			t.Errorf("expected error %v got %v", te, e)
+		}
+	})
+}
+
+func assertOFXTransactions(t *testing.T, expected, got Transactions) {
+	t.Helper()
+	if len(expected) != len(got) {
+		t.Fatalf("expected %d transactions got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		e, g := expected[i], got[i]
+		// the dates are compared as instants, the location of the date is the
+		// offset of the statement
+		if !e.Date.Equal(g.Date) {
+			t.Errorf("expected date %s got %s", e.Date, g.Date)
+		}
+		g.Date = e.Date
+		if !EqualTransaction(e, g) {
+			t.Errorf("expected transaction %v got %v", e, g)
+		}
+		if len(g.Items) == 1 && g.Items[0].Amount != e.Items[0].Amount {
+			t.Errorf("expected amount %v got %v", e.Items[0].Amount, g.Items[0].Amount)
+		}
+	}
+}
+
+func TestOFXDate(t *testing.T) {
+	tt := []struct {
+		s  string
+		t  time.Time
+		ok bool
+	}{
+		{"20220618", timeMustParse("2022-06-18T00:00:00Z"), true},
+		{"202206181526", timeMustParse("2022-06-18T15:26:00Z"), true},
+		{"20220618152622.123", timeMustParse("2022-06-18T15:26:22Z"), true},
+		{"20220618152622[-5:EST]", timeMustParse("2022-06-18T20:26:22Z"), true},
+		{"20220618152622.000[+5.5:IST]", timeMustParse("2022-06-18T09:56:22Z"), true},
+		{"2022-06-18", time.Time{}, false},
+		{"20220618[x:UTC]", time.Time{}, false},
+		{"20221318", time.Time{}, false},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.s)
+		t.Run(name, func(t *testing.T) {
+			d, ok := ofxDate(tc.s)
+			if ok != tc.ok || !d.Equal(tc.t) {
+				t.Errorf("expected %s %t got %s %t", tc.t, tc.ok, d, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package bankserv
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order of the day, month and year of the dates of a QIF
+// file, which differs between the locales of the program which exported
+// the file.
+type DateOrder int
+
+const (
+	// DateOrderAuto detects the order from the dates of the file. A file in
+	// which it is not possible to tell the order is read as MonthDayYear.
+	DateOrderAuto DateOrder = iota
+	// MonthDayYear is the order of US dates, such as 06/18/2022 or 6/18'22.
+	MonthDayYear
+	// DayMonthYear is the order of most other locales, such as 18/06/2022 or
+	// 18.06.2022.
+	DayMonthYear
+)
+
+// QIFOptions configures how a QIF file is read and written.
+type QIFOptions struct {
+	// DateOrder is the order of the dates of the file. Dates in the ISO 8601
+	// format, 2022-06-18, are always read. Files are written as MonthDayYear,
+	// unless the order is DayMonthYear.
+	DateOrder DateOrder
+	// Encoding is the character encoding of the file which is read, files
+	// are always written as UTF-8.
+	Encoding Encoding
+	// Currency is the currency of the amounts of the file which is read, if
+	// empty the DefaultCurrency is used.
+	Currency string
+}
+
+// qifTypes are the types of QIF accounts which have bank transactions.
+var qifTypes = stringSet([]string{"BANK", "CASH", "CCARD", "OTH A", "OTH L"})
+
+// qifRecord is the lines of a single transaction of a QIF file.
+type qifRecord struct {
+	line   int
+	fields [][2]string
+}
+
+// ParseQIF reads the transactions of the bank account from the bank, cash
+// and credit card sections of a QIF file. Investment, category and other
+// sections are ignored.
+//
+// The payee (P) is the description of the transaction and the number (N)
+// is its ExternalID. A transaction without splits has a single item with the
+// memo (M), amount (T) and category (L) of the transaction, and a
+// transaction with splits has an item for every split with the memo (E),
+// amount ($) and category (S) of the split. A category and its class,
+// "Groceries/Household", are read as a tag for each part.
+//
+// The transactions which are not able to be parsed are skipped and reported
+// in an error of the kind ErrValidation keyed by the line of the
+// transaction, for example "line 12".
+func ParseQIF(r io.Reader, accountUUID uuid.UUID, opts QIFOptions) (Transactions, dutil.Error) {
+	xb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Transactions{}, dutil.NewErr(500, "read", []string{err.Error()})
+	}
+
+	var records []qifRecord
+	section := "BANK"
+	rec := qifRecord{}
+	sc := bufio.NewScanner(strings.NewReader(opts.Encoding.decode(xb)))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if s[0] == '!' {
+			header := strings.ToUpper(strings.TrimSpace(s[1:]))
+			switch {
+			case strings.HasPrefix(header, "TYPE:"):
+				section = strings.TrimSpace(header[len("TYPE:"):])
+			case strings.HasPrefix(header, "OPTION:"), strings.HasPrefix(header, "CLEAR:"):
+				continue
+			default:
+				// a list of accounts or other data which is not a transaction
+				section = header
+			}
+			rec = qifRecord{}
+			continue
+		}
+		if s[0] == '^' {
+			if qifTypes[section] && len(rec.fields) > 0 {
+				records = append(records, rec)
+			}
+			rec = qifRecord{}
+			continue
+		}
+		if len(rec.fields) == 0 {
+			rec.line = line
+		}
+		rec.fields = append(rec.fields, [2]string{s[:1], strings.TrimSpace(s[1:])})
+	}
+	if err := sc.Err(); err != nil {
+		return Transactions{}, newError(dutil.NewErr(400, "qif", []string{err.Error()}))
+	}
+
+	order := opts.DateOrder
+	if order == DateOrderAuto {
+		order = qifDetectDateOrder(records)
+	}
+	xt := Transactions{}
+	v := validation{}
+	for _, rec := range records {
+		t, reasons := qifTransaction(rec, order, opts.Currency)
+		if len(reasons) > 0 {
+			v.add(fmt.Sprintf("line %d", rec.line), reasons...)
+			continue
+		}
+		t.AccountUUID = accountUUID
+		xt = append(xt, t)
+	}
+	return xt, v.err()
+}
+
+// qifTransaction returns the transaction of a record, or the reasons the
+// record is not able to be parsed.
+func qifTransaction(rec qifRecord, order DateOrder, currency string) (Transaction, []string) {
+	var reasons []string
+	t := Transaction{Active: true}
+	single := Item{SKU: Units(1), Amount: NewMoney(0, currency), Active: true}
+	hasDate, hasAmount := false, false
+	var splits Items
+	for _, f := range rec.fields {
+		code, value := f[0], f[1]
+		switch code {
+		case "D":
+			d, ok := qifDate(value, order)
+			if !ok {
+				reasons = append(reasons, fmt.Sprintf("invalid date '%s'", value))
+			}
+			t.Date, hasDate = d, true
+		case "T", "U":
+			a, ok := qifMoney(value, currency)
+			if !ok {
+				reasons = append(reasons, fmt.Sprintf("invalid amount '%s'", value))
+			}
+			single.Amount, hasAmount = a, true
+		case "P":
+			t.Description = value
+		case "N":
+			t.ExternalID = value
+		case "M":
+			single.Description = value
+		case "L":
+			single.Tags = qifTags(value)
+		case "S":
+			splits = append(splits, Item{
+				SKU:    Units(1),
+				Amount: NewMoney(0, currency),
+				Tags:   qifTags(value),
+				Active: true,
+			})
+		case "E", "$":
+			if len(splits) == 0 {
+				reasons = append(reasons, fmt.Sprintf("split '%s%s' without a category", code, value))
+				continue
+			}
+			i := &splits[len(splits)-1]
+			if code == "E" {
+				i.Description = value
+				continue
+			}
+			a, ok := qifMoney(value, currency)
+			if !ok {
+				reasons = append(reasons, fmt.Sprintf("invalid split amount '%s'", value))
+			}
+			i.Amount = a
+		}
+	}
+	if !hasDate {
+		reasons = append(reasons, "no date")
+	}
+	if !hasAmount && len(splits) == 0 {
+		reasons = append(reasons, "no amount")
+	}
+	if len(reasons) > 0 {
+		return Transaction{}, reasons
+	}
+	if len(splits) > 0 {
+		t.Items = splits
+	} else {
+		t.Items = Items{single}
+	}
+	return t, nil
+}
+
+// qifTags returns the tags of a category of a QIF file, which are the
+// category and its class separated by a slash, without the empty parts.
+func qifTags(category string) []Tag {
+	var xt []Tag
+	for _, s := range strings.Split(category, "/") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			xt = append(xt, Tag{Tag: s, Active: true})
+		}
+	}
+	return xt
+}
+
+// qifDateParts splits a QIF date, such as 6/18'22, 06-18-2022 or
+// 18.06.2022, into its three numbers. The apostrophe which Quicken uses
+// before the year of the dates after 1999 is a separator.
+func qifDateParts(s string) ([3]int, bool) {
+	var parts [3]int
+	fields := strings.FieldsFunc(strings.TrimSpace(s), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\'' || r == ' '
+	})
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// qifDetectDateOrder returns DayMonthYear if the first number of any of the
+// dates of the records is greater than 12, otherwise MonthDayYear.
+func qifDetectDateOrder(records []qifRecord) DateOrder {
+	for _, rec := range records {
+		for _, f := range rec.fields {
+			if f[0] != "D" {
+				continue
+			}
+			parts, ok := qifDateParts(f[1])
+			if !ok || parts[0] > 31 {
+				// an ISO 8601 date starts with the year
+				continue
+			}
+			if parts[0] > 12 {
+				return DayMonthYear
+			}
+		}
+	}
+	return MonthDayYear
+}
+
+// qifDate parses a QIF date in the order passed to the function. A year of
+// two digits is in the 1900s from 70 and in the 2000s before 70.
+func qifDate(s string, order DateOrder) (time.Time, bool) {
+	parts, ok := qifDateParts(s)
+	if !ok {
+		return time.Time{}, false
+	}
+	var y, m, d int
+	switch {
+	case parts[0] > 31:
+		y, m, d = parts[0], parts[1], parts[2]
+	case order == DayMonthYear:
+		d, m, y = parts[0], parts[1], parts[2]
+	default:
+		m, d, y = parts[0], parts[1], parts[2]
+	}
+	if y < 100 {
+		if y < 70 {
+			y += 2000
+		} else {
+			y += 1900
+		}
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Day() != d || int(t.Month()) != m {
+		// the date does not exist, such as 31/02
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// qifMoney parses a QIF amount, which may have thousands separators. The
+// last of a period or comma followed by one or two digits is the decimal
+// separator.
+func qifMoney(s string, currency string) (Money, bool) {
+	v := strings.Replace(strings.TrimSpace(s), " ", "", -1)
+	dot, comma := strings.LastIndexByte(v, '.'), strings.LastIndexByte(v, ',')
+	decimal := byte('.')
+	if comma > dot && len(v)-comma-1 <= 2 {
+		decimal = ','
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == decimal:
+			b.WriteByte('.')
+		case c == '.' || c == ',':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	m, e := ParseMoney(b.String(), currency)
+	return m, e == nil
+}
+
+// WriteQIF writes the transactions as the bank section of a QIF file. A
+// transaction with more than one item is written with a split for every
+// item, the amount of an item is its amount minus its discount and the tags
+// of an item are written as the category and its class, separated by a
+// slash. Dates are written without the time of the day.
+//
+// QIF does not have every field of a transaction, therefore, ParseQIF does
+// not read the file back into the same transactions:
+//   - the UUIDs of the transactions, items and tags are not written;
+//   - the ValueDate and CounterpartUUID of a transaction and the create and
+//     update dates are not written;
+//   - the SKU of an item is not written, an item is read back with a SKU of
+//     Units(1);
+//   - transactions and items are read back active;
+//   - the currency of the amounts is not written, the amounts are read back
+//     in the currency of the QIFOptions;
+//   - the discount of an item is subtracted from its amount, the item is
+//     read back with the net amount and without a discount;
+//   - a transaction without items is read back with a single item with an
+//     amount of zero;
+//   - the name of a tag with a slash is read back as two tags.
+func WriteQIF(w io.Writer, xt Transactions, opts QIFOptions) dutil.Error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("!Type:Bank\n")
+	for _, t := range xt {
+		net, e := t.Net()
+		if e != nil {
+			return e
+		}
+		bw.WriteString("D" + qifFormatDate(t.Date, opts.DateOrder) + "\n")
+		bw.WriteString("T" + net.Decimal() + "\n")
+		if t.ExternalID != "" {
+			bw.WriteString("N" + qifLine(t.ExternalID) + "\n")
+		}
+		if t.Description != "" {
+			bw.WriteString("P" + qifLine(t.Description) + "\n")
+		}
+		switch {
+		case len(t.Items) == 1:
+			i := t.Items[0]
+			if i.Description != "" {
+				bw.WriteString("M" + qifLine(i.Description) + "\n")
+			}
+			if c := qifCategory(i.Tags); c != "" {
+				bw.WriteString("L" + c + "\n")
+			}
+		case len(t.Items) > 1:
+			for _, i := range t.Items {
+				amount := i.Amount
+				if !i.Discount.IsZero() {
+					amount, e = i.Amount.Sub(i.Discount)
+					if e != nil {
+						return e
+					}
+				}
+				bw.WriteString("S" + qifCategory(i.Tags) + "\n")
+				if i.Description != "" {
+					bw.WriteString("E" + qifLine(i.Description) + "\n")
+				}
+				bw.WriteString("$" + amount.Decimal() + "\n")
+			}
+		}
+		bw.WriteString("^\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return dutil.NewErr(500, "write", []string{err.Error()})
+	}
+	return nil
+}
+
+// qifFormatDate formats a date of a QIF file in the order passed to the
+// function with a four digit year.
+func qifFormatDate(t time.Time, order DateOrder) string {
+	if order == DayMonthYear {
+		return t.Format("02/01/2006")
+	}
+	return t.Format("01/02/2006")
+}
+
+// qifCategory returns the category of the tags, the names of the tags
+// separated by a slash.
+func qifCategory(tags []Tag) string {
+	xs := make([]string, 0, len(tags))
+	for _, t := range tags {
+		xs = append(xs, qifLine(t.Tag))
+	}
+	return strings.Join(xs, "/")
+}
+
+// qifLine returns the text as a single line of a QIF file.
+func qifLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
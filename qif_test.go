@@ -0,0 +1,319 @@
+package bankserv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+)
+
+const qifStatement = `!Option:AutoSwitch
+!Account
+NCheque
+TBank
+^
+!Clear:AutoSwitch
+!Type:Bank
+D6/18'22
+T-1,236.19
+N1001
+PSUPERSPAR JEFFREYS BAY
+Mweekly shop
+LGroceries:Food/Household
+^
+D06/20/2022
+T-300.00
+PPICK N PAY
+SGroceries
+Emilk
+$-100.00
+SHome
+$-200.00
+^
+D6/31/2022
+T-1.00
+PINVALID
+^
+!Type:Invst
+D6/18'22
+NBuy
+YACME
+^
+!Type:Bank
+D7/1/22
+T2.500,50
+PSALARY
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	accountUUID := uuid.MustParse("032203af-6002-4abc-9982-73c577add8df")
+	tag := func(names ...string) []Tag {
+		var xt []Tag
+		for _, n := range names {
+			xt = append(xt, Tag{Tag: n, Active: true})
+		}
+		return xt
+	}
+	item := func(description string, amount int64, tags []Tag) Item {
+		return Item{Description: description, SKU: Units(1), Amount: NewMoney(amount, "ZAR"), Tags: tags, Active: true}
+	}
+
+	xt, e := ParseQIF(strings.NewReader(qifStatement), accountUUID, QIFOptions{Currency: "ZAR"})
+	te := dutil.NewErr(400, "line 24", []string{"invalid date '6/31/2022'"})
+	if !dutil.ErrorEqual(te, e) || !errors.Is(e, ErrValidation) {
+		t.Errorf("expected error %v got %v", te, e)
+	}
+	expected := Transactions{
+		{
+			AccountUUID: accountUUID,
+			ExternalID:  "1001",
+			Date:        timeMustParse("2022-06-18T00:00:00Z"),
+			Description: "SUPERSPAR JEFFREYS BAY",
+			Items:       Items{item("weekly shop", -123619, tag("Groceries:Food", "Household"))},
+			Active:      true,
+		},
+		{
+			AccountUUID: accountUUID,
+			Date:        timeMustParse("2022-06-20T00:00:00Z"),
+			Description: "PICK N PAY",
+			Items: Items{
+				item("milk", -10000, tag("Groceries")),
+				item("", -20000, tag("Home")),
+			},
+			Active: true,
+		},
+		{
+			AccountUUID: accountUUID,
+			Date:        timeMustParse("2022-07-01T00:00:00Z"),
+			Description: "SALARY",
+			Items:       Items{item("", 250050, nil)},
+			Active:      true,
+		},
+	}
+	if !EqualTransactions(expected, xt) {
+		t.Errorf("expected %v got %v", expected, xt)
+	}
+	for i := range xt {
+		for j := range xt[i].Items {
+			if i < len(expected) && j < len(expected[i].Items) && xt[i].Items[j].Amount != expected[i].Items[j].Amount {
+				t.Errorf("expected amount %v got %v", expected[i].Items[j].Amount, xt[i].Items[j].Amount)
+			}
+		}
+	}
+}
+
+func TestQIFDate(t *testing.T) {
+	tt := []struct {
+		s     string
+		order DateOrder
+		date  string
+	}{
+		{"06/18/2022", MonthDayYear, "2022-06-18"},
+		{"6/18'22", MonthDayYear, "2022-06-18"},
+		{"6/18/99", MonthDayYear, "1999-06-18"},
+		{"18/06/2022", DayMonthYear, "2022-06-18"},
+		{"18.06.22", DayMonthYear, "2022-06-18"},
+		{"2022-06-18", DayMonthYear, "2022-06-18"},
+		{"2022-06-18", MonthDayYear, "2022-06-18"},
+		{"18/06/2022", MonthDayYear, ""},
+		{"06/2022", MonthDayYear, ""},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.s)
+		t.Run(name, func(t *testing.T) {
+			d, ok := qifDate(tc.s, tc.order)
+			if tc.date == "" {
+				if ok {
+					t.Errorf("expected an invalid date got %s", d)
+				}
+				return
+			}
+			if !ok || d.Format("2006-01-02") != tc.date {
+				t.Errorf("expected %s got %s %t", tc.date, d, ok)
+			}
+		})
+	}
+}
+
+func TestParseQIF_dateOrder(t *testing.T) {
+	qif := "!Type:Bank\nD01/02/2022\nT-1.00\n^\nD13/02/2022\nT-2.00\n^\n"
+	xt, e := ParseQIF(strings.NewReader(qif), uuid.Nil, QIFOptions{})
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	// 13/02 is only a valid day-month date
+	if len(xt) != 2 || xt[0].Date.Month() != time.February || xt[0].Date.Day() != 1 {
+		t.Errorf("expected the dates to be read as day, month, year got %v", xt)
+	}
+}
+
+func TestWriteQIF(t *testing.T) {
+	groceriesTag := groceries
+	household := Tag{UUID: uuid.New(), Tag: "Household", Active: true}
+	xt := Transactions{
+		{
+			AccountUUID: superspar.AccountUUID,
+			ExternalID:  "1001",
+			Date:        timeMustParse("2022-06-18T00:00:00Z"),
+			Description: "SUPERSPAR JEFFREYS BAY",
+			Items: Items{
+				{Description: "weekly shop", SKU: Units(1), Amount: NewMoney(-123619, "ZAR"), Tags: []Tag{groceriesTag, household}, Active: true},
+			},
+			Active: true,
+		},
+		{
+			AccountUUID: superspar.AccountUUID,
+			Date:        timeMustParse("2022-06-20T00:00:00Z"),
+			Description: "PICK N PAY",
+			Items: Items{
+				{Description: "milk", SKU: Units(1), Amount: NewMoney(-10000, "ZAR"), Tags: []Tag{groceriesTag}, Active: true},
+				{SKU: Units(1), Amount: NewMoney(-20000, "ZAR"), Active: true},
+			},
+			Active: true,
+		},
+	}
+
+	for _, order := range []DateOrder{MonthDayYear, DayMonthYear} {
+		t.Run(fmt.Sprintf("date order %d", order), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if e := WriteQIF(buf, xt, QIFOptions{DateOrder: order}); e != nil {
+				t.Fatalf("unexpected error %v", e)
+			}
+			o, e := ParseQIF(buf, superspar.AccountUUID, QIFOptions{DateOrder: order})
+			if e != nil {
+				t.Fatalf("unexpected error %v", e)
+			}
+			// the tags are read without their UUIDs
+			expected := make(Transactions, len(xt))
+			copy(expected, xt)
+			for i := range expected {
+				items := make(Items, len(expected[i].Items))
+				for j, item := range expected[i].Items {
+					item.Tags = qifTags(qifCategory(item.Tags))
+					items[j] = item
+				}
+				expected[i].Items = items
+			}
+			if !EqualTransactions(expected, o) {
+				t.Errorf("expected %v got %v\n%s", expected, o, buf.String())
+			}
+		})
+	}
+
+	t.Run("fields without a QIF field", func(t *testing.T) {
+		written := Transactions{
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-21T00:00:00Z"),
+				Description: "SPAR",
+				Items: Items{
+					{Description: "bread", SKU: Units(1), Amount: NewMoney(-2500, "ZAR"), Discount: NewMoney(-500, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-22T00:00:00Z"),
+				Description: "PICK N PAY",
+				Items: Items{
+					{Description: "milk", SKU: Units(1), Amount: NewMoney(-10000, "ZAR"), Discount: NewMoney(-1000, "ZAR"), Active: true},
+					{Description: "eggs", SKU: Units(1), Amount: NewMoney(-5000, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-23T00:00:00Z"),
+				Description: "NO ITEMS",
+				Active:      true,
+			},
+			{
+				AccountUUID:     superspar.AccountUUID,
+				CounterpartUUID: uuid.MustParse("5f0c3a1e-7c9b-4d2a-9e61-0b8f4c2d7a13"),
+				Date:            timeMustParse("2022-06-24T00:00:00Z"),
+				ValueDate:       timeMustParsePtr("2022-06-25T00:00:00Z"),
+				Description:     "TRANSFER",
+				Items: Items{
+					{Description: "dollars", SKU: Units(3), Amount: NewMoney(-3000, "USD")},
+				},
+			},
+		}
+		// the discount is part of the amount and a transaction without items
+		// has an item of zero
+		read := Transactions{
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-21T00:00:00Z"),
+				Description: "SPAR",
+				Items: Items{
+					{Description: "bread", SKU: Units(1), Amount: NewMoney(-2000, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-22T00:00:00Z"),
+				Description: "PICK N PAY",
+				Items: Items{
+					{Description: "milk", SKU: Units(1), Amount: NewMoney(-9000, "ZAR"), Active: true},
+					{Description: "eggs", SKU: Units(1), Amount: NewMoney(-5000, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-23T00:00:00Z"),
+				Description: "NO ITEMS",
+				Items: Items{
+					{SKU: Units(1), Amount: NewMoney(0, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+			// the value date, counterpart, SKU, currency and active state are
+			// not written
+			{
+				AccountUUID: superspar.AccountUUID,
+				Date:        timeMustParse("2022-06-24T00:00:00Z"),
+				Description: "TRANSFER",
+				Items: Items{
+					{Description: "dollars", SKU: Units(1), Amount: NewMoney(-3000, "ZAR"), Active: true},
+				},
+				Active: true,
+			},
+		}
+		buf := &bytes.Buffer{}
+		if e := WriteQIF(buf, written, QIFOptions{}); e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		o, e := ParseQIF(buf, superspar.AccountUUID, QIFOptions{})
+		if e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+		if !EqualTransactions(read, o) {
+			t.Errorf("expected %v got %v\n%s", read, o, buf.String())
+		}
+		for i := range read {
+			wn, _ := written[i].Net()
+			on, _ := o[i].Net()
+			// the currency of the amount is not written
+			if wn.Decimal() != on.Decimal() {
+				t.Errorf("expected the net amount %v of transaction %d got %v", wn, i, on)
+			}
+		}
+	})
+
+	t.Run("format", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		_ = WriteQIF(buf, xt[1:], QIFOptions{DateOrder: DayMonthYear})
+		expected := "!Type:Bank\nD20/06/2022\nT-300.00\nPPICK N PAY\nSgroceries\nEmilk\n$-100.00\nS\n$-200.00\n^\n"
+		if buf.String() != expected {
+			t.Errorf("expected %q got %q", expected, buf.String())
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package bankserv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dottics/dutil"
+	"strconv"
+	"strings"
+)
+
+// quantityExponent is the number of decimal digits of a Quantity.
+const quantityExponent = 3
+
+// Quantity is an exact quantity of an item, such as 3 units or 1.25 kg. The
+// quantity is stored as an integer number of thousandths, therefore, a
+// quantity such as 3.15 is never changed the way a float32 quantity is.
+//
+// A Quantity is marshalled to a JSON number without trailing zeros, and can
+// be unmarshalled from a JSON number or string.
+type Quantity struct {
+	Thousandths int64
+}
+
+// Units creates a Quantity of a number of whole units.
+func Units(n int64) Quantity {
+	return Quantity{Thousandths: n * 1000}
+}
+
+// ParseQuantity parses a decimal string such as "3.15" to a Quantity. Values
+// with more than three decimals are rounded half away from zero.
+func ParseQuantity(s string) (Quantity, dutil.Error) {
+	n, ok, inRange := parseDecimal(s, quantityExponent)
+	if !ok {
+		return Quantity{}, newError(dutil.NewErr(400, "quantity", []string{fmt.Sprintf("invalid quantity '%s'", s)}))
+	}
+	if !inRange {
+		return Quantity{}, newError(dutil.NewErr(400, "quantity", []string{fmt.Sprintf("quantity '%s' out of range", s)}))
+	}
+	return Quantity{Thousandths: n}, nil
+}
+
+// Decimal returns the quantity as a decimal string without trailing zeros,
+// for example "3.15" or "1".
+func (q Quantity) Decimal() string {
+	s := strings.TrimRight(formatDecimal(q.Thousandths, quantityExponent), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// String returns the quantity as a decimal string, see Decimal.
+func (q Quantity) String() string {
+	return q.Decimal()
+}
+
+// Float64 returns the quantity as a float. The float is only an
+// approximation of the quantity and should only be used for display purposes.
+func (q Quantity) Float64() float64 {
+	f, _ := strconv.ParseFloat(q.Decimal(), 64)
+	return f
+}
+
+// Sign returns -1 if the quantity is negative, 0 if the quantity is zero and
+// +1 if the quantity is positive.
+func (q Quantity) Sign() int {
+	switch {
+	case q.Thousandths < 0:
+		return -1
+	case q.Thousandths > 0:
+		return 1
+	}
+	return 0
+}
+
+// MarshalJSON marshals the quantity to a JSON number.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.Decimal()), nil
+}
+
+// UnmarshalJSON unmarshals a JSON number, a JSON string containing a decimal
+// number or null to a Quantity.
+func (q *Quantity) UnmarshalJSON(xb []byte) error {
+	xb = bytes.TrimSpace(xb)
+	if bytes.Equal(xb, []byte("null")) {
+		q.Thousandths = 0
+		return nil
+	}
+	v := string(xb)
+	if len(xb) > 0 && xb[0] == '"' {
+		err := json.Unmarshal(xb, &v)
+		if err != nil {
+			return err
+		}
+	} else if strings.ContainsAny(v, "eE") {
+		// a number in exponent notation is only sent by float encoders
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		v = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	a, e := ParseQuantity(v)
+	if e != nil {
+		return e
+	}
+	*q = a
+	return nil
+}
@@ -0,0 +1,138 @@
+package bankserv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tt := []struct {
+		name string
+		s    string
+		q    Quantity
+		e    dutil.Error
+	}{
+		{
+			name: "empty",
+			s:    "",
+			e:    dutil.NewErr(400, "quantity", []string{"invalid quantity ''"}),
+		},
+		{
+			name: "not a number",
+			s:    "3 kg",
+			e:    dutil.NewErr(400, "quantity", []string{"invalid quantity '3 kg'"}),
+		},
+		{
+			name: "out of range",
+			s:    "9223372036854775.808",
+			e:    dutil.NewErr(400, "quantity", []string{"quantity '9223372036854775.808' out of range"}),
+		},
+		{
+			name: "whole units",
+			s:    "3",
+			q:    Units(3),
+		},
+		{
+			name: "fraction",
+			s:    "3.15",
+			q:    Quantity{Thousandths: 3150},
+		},
+		{
+			name: "rounded",
+			s:    "-1.2345",
+			q:    Quantity{Thousandths: -1235},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			q, e := ParseQuantity(tc.s)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && !errors.Is(e, ErrValidation) {
+				t.Errorf("expected a validation error got %v", e)
+			}
+			if q != tc.q {
+				t.Errorf("expected quantity %v got %v", tc.q, q)
+			}
+		})
+	}
+}
+
+func TestQuantity_JSON(t *testing.T) {
+	tt := []struct {
+		name string
+		json string
+		q    Quantity
+		out  string
+		err  bool
+	}{
+		{
+			name: "integer",
+			json: `1`,
+			q:    Units(1),
+			out:  `1`,
+		},
+		{
+			name: "float32 payload",
+			json: `3.1500000953674316`,
+			q:    Quantity{Thousandths: 3150},
+			out:  `3.15`,
+		},
+		{
+			name: "string",
+			json: `"0.250"`,
+			q:    Quantity{Thousandths: 250},
+			out:  `0.25`,
+		},
+		{
+			name: "exponent notation",
+			json: `1.5e1`,
+			q:    Units(15),
+			out:  `15`,
+		},
+		{
+			name: "null",
+			json: `null`,
+			q:    Quantity{},
+			out:  `0`,
+		},
+		{
+			name: "invalid string",
+			json: `"three"`,
+			err:  true,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			q := Quantity{}
+			err := json.Unmarshal([]byte(tc.json), &q)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if q != tc.q {
+				t.Errorf("expected quantity %v got %v", tc.q, q)
+			}
+			xb, err := json.Marshal(q)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if string(xb) != tc.out {
+				t.Errorf("expected %s got %s", tc.out, xb)
+			}
+		})
+	}
+}
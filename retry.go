@@ -0,0 +1,180 @@
+package bankserv
+
+import (
+	"bytes"
+	"context"
+	"github.com/dottics/dutil"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how a Service retries exchanges with the
+// bank-service which failed because of a transient failure. A transient
+// failure is a failure to send the request, such as a connection reset, or a
+// response with the status 502, 503 or 504.
+//
+// Only the idempotent GET, PUT and DELETE exchanges are retried, unless
+// RetryPOST is set. The zero RetryPolicy does not retry any exchange.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of an exchange, including
+	// the first attempt.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, the delay doubles with
+	// every retry after the first retry.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between two attempts, if zero the delay
+	// is not limited.
+	MaxDelay time.Duration
+	// Jitter is the fraction, between 0 and 1, of the delay which is random,
+	// such that clients do not retry at the same time.
+	Jitter float64
+	// RetryPOST retries POST exchanges as well. Only set RetryPOST if the
+	// bank-service is able to detect duplicate creates. The idempotency key
+	// of a create is the same for every attempt.
+	RetryPOST bool
+}
+
+// DefaultRetryPolicy is a RetryPolicy which retries an exchange up to two
+// times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+}
+
+// SetRetryPolicy sets the policy used to retry exchanges with the
+// bank-service. SetRetryPolicy should be called before the Service is used
+// by more than one goroutine.
+func (s *Service) SetRetryPolicy(p RetryPolicy) {
+	s.retry = p
+}
+
+// retryable reports whether the attempt of an exchange with the method may be
+// retried based on the response or error of the attempt.
+func (p RetryPolicy) retryable(method string, res *http.Response, e dutil.Error) bool {
+	switch method {
+	case "GET", "PUT", "DELETE":
+	case "POST":
+		if !p.RetryPOST {
+			return false
+		}
+	default:
+		return false
+	}
+	if e != nil {
+		// transmit returns errors with the key "request" when the request
+		// could not be sent or the response could not be received
+		_, ok := dutil.Inst(e).Errors["request"]
+		return ok
+	}
+	switch res.StatusCode {
+	case 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// delay returns how long to wait before the next attempt after the attempt
+// with number attempt failed. If the response has a Retry-After header the
+// delay of the header is returned, limited to the MaxDelay of the policy.
+func (p RetryPolicy) delay(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if d, ok := retryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxDelay > 0 && d > p.MaxDelay {
+				d = p.MaxDelay
+			}
+			return d
+		}
+	}
+	d := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		j := time.Duration(p.Jitter * float64(d))
+		if j > 0 {
+			d -= time.Duration(rand.Int63n(int64(j) + 1))
+		}
+	}
+	return d
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date, to a delay from now.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// send sends the request to the bank-service and retries the request based on
+// the retry policy of the service. The header and payload are sent again with
+// every attempt. A request which is not able to be created is not retried,
+// every attempt would fail the same way.
+func (s *Service) send(ctx context.Context, method, url string, header http.Header, payload []byte) (*http.Response, dutil.Error) {
+	p := s.retry
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(payload)
+		}
+		req, e := s.buildRequest(ctx, method, url, header, body)
+		if e != nil {
+			return nil, e
+		}
+		res, e := s.transmit(ctx, req)
+		if attempt >= p.MaxAttempts || !p.retryable(method, res, e) {
+			return res, e
+		}
+
+		d := p.delay(attempt, res)
+		if res != nil {
+			// drain the body so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+		e = sleep(ctx, d)
+		if e != nil {
+			return nil, e
+		}
+	}
+}
+
+// sleep waits for the duration d or until the context is done, in which case
+// a context error is returned.
+func sleep(ctx context.Context, d time.Duration) dutil.Error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
+}
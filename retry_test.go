@@ -0,0 +1,399 @@
+package bankserv
+
+import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/johannesscr/micro/microtest"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_retryable(t *testing.T) {
+	requestErr := dutil.NewErr(500, "request", []string{"connection reset by peer"})
+	contextErr := dutil.NewErr(499, "context", []string{"context canceled"})
+
+	tt := []struct {
+		name   string
+		p      RetryPolicy
+		method string
+		status int
+		e      dutil.Error
+		o      bool
+	}{
+		{
+			name:   "GET service unavailable",
+			method: "GET",
+			status: 503,
+			o:      true,
+		},
+		{
+			name:   "PUT bad gateway",
+			method: "PUT",
+			status: 502,
+			o:      true,
+		},
+		{
+			name:   "DELETE gateway timeout",
+			method: "DELETE",
+			status: 504,
+			o:      true,
+		},
+		{
+			name:   "GET internal server error",
+			method: "GET",
+			status: 500,
+			o:      false,
+		},
+		{
+			name:   "GET not found",
+			method: "GET",
+			status: 404,
+			o:      false,
+		},
+		{
+			name:   "GET connection reset",
+			method: "GET",
+			e:      requestErr,
+			o:      true,
+		},
+		{
+			name:   "GET context cancelled",
+			method: "GET",
+			e:      contextErr,
+			o:      false,
+		},
+		{
+			name:   "POST service unavailable",
+			method: "POST",
+			status: 503,
+			o:      false,
+		},
+		{
+			name:   "POST service unavailable with opt in",
+			p:      RetryPolicy{RetryPOST: true},
+			method: "POST",
+			status: 503,
+			o:      true,
+		},
+		{
+			name:   "PATCH service unavailable",
+			p:      RetryPolicy{RetryPOST: true},
+			method: "PATCH",
+			status: 503,
+			o:      false,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			var res *http.Response
+			if tc.e == nil {
+				res = &http.Response{StatusCode: tc.status}
+			}
+			o := tc.p.retryable(tc.method, res, tc.e)
+			if o != tc.o {
+				t.Errorf("expected retryable %t got %t", tc.o, o)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+	tt := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		d          time.Duration
+	}{
+		{
+			name:    "first retry",
+			attempt: 1,
+			d:       100 * time.Millisecond,
+		},
+		{
+			name:    "third retry",
+			attempt: 3,
+			d:       400 * time.Millisecond,
+		},
+		{
+			name:    "maximum delay",
+			attempt: 10,
+			d:       time.Second,
+		},
+		{
+			name:       "retry after",
+			attempt:    1,
+			retryAfter: "1",
+			d:          time.Second,
+		},
+		{
+			name:       "retry after limited to the maximum delay",
+			attempt:    1,
+			retryAfter: "3600",
+			d:          time.Second,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tc.retryAfter != "" {
+				res.Header.Set("Retry-After", tc.retryAfter)
+			}
+			d := p.delay(tc.attempt, res)
+			if d != tc.d {
+				t.Errorf("expected delay %v got %v", tc.d, d)
+			}
+		})
+	}
+
+	t.Run("jitter", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			d := p.delay(1, nil)
+			if d < 50*time.Millisecond || d > 100*time.Millisecond {
+				t.Fatalf("expected delay between 50ms and 100ms got %v", d)
+			}
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := timeMustParse("2022-06-20T08:00:00Z")
+	tt := []struct {
+		name string
+		v    string
+		d    time.Duration
+		ok   bool
+	}{
+		{
+			name: "no header",
+			v:    "",
+			ok:   false,
+		},
+		{
+			name: "seconds",
+			v:    "120",
+			d:    2 * time.Minute,
+			ok:   true,
+		},
+		{
+			name: "negative seconds",
+			v:    "-1",
+			ok:   false,
+		},
+		{
+			name: "http date",
+			v:    "Mon, 20 Jun 2022 08:00:30 GMT",
+			d:    30 * time.Second,
+			ok:   true,
+		},
+		{
+			name: "http date in the past",
+			v:    "Mon, 20 Jun 2022 07:00:00 GMT",
+			d:    0,
+			ok:   true,
+		},
+		{
+			name: "invalid",
+			v:    "soon",
+			ok:   false,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			d, ok := retryAfter(tc.v, now)
+			if ok != tc.ok {
+				t.Errorf("expected ok %t got %t", tc.ok, ok)
+			}
+			if d != tc.d {
+				t.Errorf("expected delay %v got %v", tc.d, d)
+			}
+		})
+	}
+}
+
+// connectionReset is an exchange which makes the mock server drop the
+// connection without a response, microtest is unable to write a response
+// with an invalid status.
+var connectionReset = microtest.Response{Status: 0}
+
+func TestService_retry(t *testing.T) {
+	unavailable := microtest.Response{
+		Status: 503,
+		Body:   `{"message":"ServiceUnavailable","data":{},"errors":{"service":["unavailable"]}}`,
+	}
+	badGateway := microtest.Response{
+		Status: 502,
+		Body:   `<html>Bad Gateway</html>`,
+	}
+	banks := microtest.Response{
+		Status: 200,
+		Body:   `{"message":"banks found","data":{"banks":[]},"errors":{}}`,
+	}
+	created := microtest.Response{
+		Status: 201,
+		Body:   `{"message":"bank created","data":{"bank":` + investecJSON + `},"errors":{}}`,
+	}
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		Jitter:      0.5,
+	}
+
+	tt := []struct {
+		name      string
+		p         RetryPolicy
+		method    string
+		responses []microtest.Response
+		// transmissions is the number of exchanges expected to be made
+		transmissions int
+		e             dutil.Error
+	}{
+		{
+			name:          "no retry policy",
+			method:        "GET",
+			responses:     []microtest.Response{unavailable, banks},
+			transmissions: 1,
+			e:             dutil.NewErr(503, "service", []string{"unavailable"}),
+		},
+		{
+			name:          "recover from transient failures",
+			p:             policy,
+			method:        "GET",
+			responses:     []microtest.Response{unavailable, badGateway, banks},
+			transmissions: 3,
+			e:             nil,
+		},
+		{
+			name:          "recover from connection reset",
+			p:             policy,
+			method:        "GET",
+			responses:     []microtest.Response{connectionReset, banks},
+			transmissions: 2,
+			e:             nil,
+		},
+		{
+			name:          "attempts exhausted",
+			p:             policy,
+			method:        "GET",
+			responses:     []microtest.Response{unavailable, unavailable, badGateway, banks},
+			transmissions: 3,
+			e:             dutil.NewErr(502, "response", []string{"Bad Gateway"}),
+		},
+		{
+			name:          "POST not retried",
+			p:             policy,
+			method:        "POST",
+			responses:     []microtest.Response{unavailable, created},
+			transmissions: 1,
+			e:             dutil.NewErr(503, "service", []string{"unavailable"}),
+		},
+		{
+			name: "POST retried with opt in",
+			p: RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				RetryPOST:   true,
+			},
+			method:        "POST",
+			responses:     []microtest.Response{unavailable, created},
+			transmissions: 2,
+			e:             nil,
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := NewService("")
+			s.SetRetryPolicy(tc.p)
+			ms := microtest.MockServer(s.serv)
+			// silence the mock server's log of a dropped connection
+			ms.Server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+			for _, r := range tc.responses {
+				ms.Append(&microtest.Exchange{Response: r})
+			}
+
+			var e dutil.Error
+			if tc.method == "POST" {
+				_, e = s.CreateBank(Bank{Name: "investec"})
+			} else {
+				_, e = s.GetBanks()
+			}
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && dutil.Inst(e).Status != dutil.Inst(tc.e).Status {
+				t.Errorf("expected status %d got %d", dutil.Inst(tc.e).Status, dutil.Inst(e).Status)
+			}
+			n := 0
+			for _, x := range ms.Exchanges {
+				if x.Request != nil {
+					n++
+				}
+			}
+			if n != tc.transmissions {
+				t.Errorf("expected %d transmissions got %d", tc.transmissions, n)
+			}
+		})
+	}
+
+	t.Run("retry after bounded by context", func(t *testing.T) {
+		s := NewService("")
+		s.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+		ms := microtest.MockServer(s.serv)
+		ms.Append(&microtest.Exchange{
+			Response: microtest.Response{
+				Status: 503,
+				Header: map[string][]string{"Retry-After": {"60"}},
+				Body:   unavailable.Body,
+			},
+		})
+		ms.Append(&microtest.Exchange{Response: banks})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, e := s.GetBanksContext(ctx)
+		if dutil.Inst(e).Status != 504 {
+			t.Errorf("expected status %d got %v", 504, e)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("expected the context to end the wait for the Retry-After delay")
+		}
+		if ms.Exchanges[1].Request != nil {
+			t.Errorf("expected no retry before the Retry-After delay")
+		}
+	})
+
+	t.Run("invalid request not retried", func(t *testing.T) {
+		s := NewService("")
+		s.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		res, e := s.send(ctx, "GET", "http://[::1/bank", nil, nil)
+		if res != nil {
+			t.Errorf("expected no response got %v", res)
+		}
+		if _, ok := dutil.Inst(e).Errors["request"]; !ok {
+			t.Errorf("expected a request error got %v", e)
+		}
+		if ctx.Err() != nil {
+			t.Errorf("expected the invalid request not to wait for a retry")
+		}
+	})
+}
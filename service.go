@@ -1,11 +1,24 @@
 package bankserv
 
 import (
+	"context"
+	"errors"
+	"github.com/dottics/dutil"
 	"github.com/johannesscr/micro/msp"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
 )
 
 type Service struct {
-	serv *msp.Service
+	serv        *msp.Service
+	retry       RetryPolicy
+	idempotency idempotencyRecord
+	// onResponse is called with the details of every response, if set
+	onResponse func(context.Context, Response)
+	// noBatch is set once the bank-service does not have a batch endpoint
+	noBatch int32
 }
 
 const microServiceName string = "bank"
@@ -24,3 +37,80 @@ func NewService(token string) *Service {
 	}
 	return s
 }
+
+// url builds the URL for a single exchange with the bank-service from the
+// path and query string passed to the function. The base URL of the msp is
+// copied and never written to, which makes it safe to share a Service
+// between goroutines.
+func (s *Service) url(path string, qs url.Values) string {
+	u := s.serv.URL
+	u.Path = path
+	u.RawQuery = qs.Encode()
+	return u.String()
+}
+
+// buildRequest creates a request to the bank-service which is bound to the
+// context passed to the function. The default headers of the msp are copied
+// to the request, such that the headers are never shared between requests.
+// The header passed to the function is added to the default headers. A
+// request which is not able to be created, such as a request with an invalid
+// URL, returns an error with the key "request".
+func (s *Service) buildRequest(ctx context.Context, method, url string, header http.Header, payload io.Reader) (*http.Request, dutil.Error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, payload)
+	if err != nil {
+		e := dutil.NewErr(500, "request", []string{err.Error()})
+		return nil, e
+	}
+	// set the default service headers
+	req.Header = s.serv.Header.Clone()
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return req, nil
+}
+
+// transmit sends the request to the bank-service. If the context of the
+// request is cancelled or its deadline is exceeded before the exchange
+// completes, the exchange is aborted and a context error is returned.
+func (s *Service) transmit(ctx context.Context, req *http.Request) (*http.Response, dutil.Error) {
+	client := http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		if e := contextError(ctx); e != nil {
+			return nil, e
+		}
+		e := dutil.NewErr(500, "request", []string{err.Error()})
+		return nil, e
+	}
+	log.Printf("- %s-service -> [%s %s] <- %d", s.serv.Name, req.Method, req.URL.String(), res.StatusCode)
+	return res, nil
+}
+
+// decode reads the body of the response. If the context is done while the
+// body is read, a context error is returned instead of the read error.
+func (s *Service) decode(ctx context.Context, res *http.Response) ([]byte, dutil.Error) {
+	xb, e := s.serv.Decode(res, nil)
+	if e != nil {
+		if ce := contextError(ctx); ce != nil {
+			return nil, ce
+		}
+		return nil, e
+	}
+	return xb, nil
+}
+
+// contextError returns an error with the key "context" if the context is done
+// otherwise it returns nil. A cancelled context has the status 499 (client
+// closed request) and an exceeded deadline has the status 504 (gateway
+// timeout).
+func contextError(ctx context.Context) dutil.Error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	status := 499
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = 504
+	}
+	return dutil.NewErr(status, "context", []string{err.Error()})
+}
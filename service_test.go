@@ -1,8 +1,17 @@
 package bankserv
 
 import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"net/http"
+	"net/url"
 	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewService(t *testing.T) {
@@ -32,3 +41,281 @@ func TestNewService(t *testing.T) {
 		)
 	}
 }
+
+func TestService_url(t *testing.T) {
+	s := NewService("")
+	s.serv.SetURL("https", "bank.dottics.com")
+
+	tt := []struct {
+		name string
+		path string
+		qs   url.Values
+		o    string
+	}{
+		{
+			name: "path only",
+			path: "/bank",
+			qs:   nil,
+			o:    "https://bank.dottics.com/bank",
+		},
+		{
+			name: "path and query string",
+			path: "/bank-account/-",
+			qs:   url.Values{"uuid": {"e6b7f986-307c-4147-a34e-f924790799bb"}},
+			o:    "https://bank.dottics.com/bank-account/-?uuid=e6b7f986-307c-4147-a34e-f924790799bb",
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			o := s.url(tc.path, tc.qs)
+			if o != tc.o {
+				t.Errorf("expected url %s got %s", tc.o, o)
+			}
+			// the base URL of the msp may never be changed
+			if s.serv.URL.Path != "" || s.serv.URL.RawQuery != "" {
+				t.Errorf("expected base url to be unchanged got %v", s.serv.URL.String())
+			}
+		})
+	}
+}
+
+// TestService_concurrent runs every method of the Service at the same time
+// with a single shared Service. Run with the race detector (go test -race)
+// to detect shared state between exchanges. Each exchange has to arrive at
+// the endpoint of the method that made it.
+func TestService_concurrent(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	// the microtest exchange queue is not safe for concurrent use, therefore
+	// the mock server handles one exchange at a time.
+	var mu sync.Mutex
+	h := ms.Server.Config.Handler
+	ms.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		h.ServeHTTP(w, r)
+	})
+
+	UUID := uuid.MustParse("e6b7f986-307c-4147-a34e-f924790799bb")
+	calls := map[string]func(){
+		"GET /bank?": func() {
+			_, _ = s.GetBanks()
+		},
+		"GET /bank/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetBank(UUID)
+		},
+		"GET /bank/-?branch_code=580105": func() {
+			_, _ = s.GetBankByBranchCode("580105")
+		},
+		"POST /bank?": func() {
+			_, _ = s.CreateBank(Bank{})
+		},
+		"PUT /bank/-?": func() {
+			_, _ = s.UpdateBank(Bank{})
+		},
+		"DELETE /bank/-?uuid=" + UUID.String(): func() {
+			_ = s.DeleteBank(UUID)
+		},
+		"GET /bank-account/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetBankAccount(UUID)
+		},
+		"GET /transaction/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetTransaction(UUID)
+		},
+		"GET /item/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetItem(UUID)
+		},
+		"GET /bank-account/user/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetUserBankAccounts(UUID)
+		},
+		"GET /bank-account/organisation/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetOrganisationBankAccounts(UUID)
+		},
+		"POST /bank-account?": func() {
+			_, _ = s.CreateBankAccount(userBankAccount)
+		},
+		"PUT /bank-account/-?": func() {
+			_, _ = s.UpdateBankAccount(userBankAccount)
+		},
+		"DELETE /bank-account/-?uuid=" + UUID.String(): func() {
+			_ = s.DeleteBankAccount(UUID)
+		},
+		"GET /transaction/bank-account/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetBankAccountTransactions(UUID)
+		},
+		"POST /transaction?": func() {
+			_, _ = s.CreateTransaction(Transaction{AccountUUID: UUID, Date: timeMustParse("2022-06-18T15:26:22Z")})
+		},
+		"POST /transaction/batch?": func() {
+			_, _ = s.CreateTransactions(Transactions{{AccountUUID: UUID, Date: timeMustParse("2022-06-18T15:26:22Z")}}, BulkOptions{})
+		},
+		"PUT /transaction/-?": func() {
+			_, _ = s.UpdateTransaction(Transaction{UUID: UUID})
+		},
+		"DELETE /transaction/-?uuid=" + UUID.String(): func() {
+			_ = s.DeleteTransaction(UUID)
+		},
+		"GET /item/transaction/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetTransactionItems(UUID)
+		},
+		"POST /item?": func() {
+			_, _ = s.CreateItem(Item{TransactionUUID: UUID})
+		},
+		"PUT /item/-?": func() {
+			_, _ = s.UpdateItem(Item{UUID: UUID})
+		},
+		"DELETE /item/-?uuid=" + UUID.String(): func() {
+			_ = s.DeleteItem(UUID)
+		},
+		"POST /item/tag?": func() {
+			_, _ = s.AddItemTag(UUID, UUID)
+		},
+		"DELETE /item/tag?item_uuid=" + UUID.String() + "&tag_uuid=" + UUID.String(): func() {
+			_, _ = s.RemoveItemTag(UUID, UUID)
+		},
+		"GET /tag/user/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetUserTags(UUID)
+		},
+		"GET /tag/organisation/-?uuid=" + UUID.String(): func() {
+			_, _ = s.GetOrganisationTags(UUID)
+		},
+		"POST /tag?": func() {
+			_, _ = s.CreateTag(groceries)
+		},
+		"PUT /tag/-?": func() {
+			_, _ = s.UpdateTag(groceries)
+		},
+		"DELETE /tag/-?uuid=" + UUID.String(): func() {
+			_ = s.DeleteTag(UUID)
+		},
+		"POST /tag/merge?": func() {
+			_, _ = s.MergeTags(UUID, []uuid.UUID{UUID})
+		},
+	}
+
+	// every method is called several times to increase contention
+	n := 5
+	for i := 0; i < n*len(calls); i++ {
+		ms.Append(&microtest.Exchange{
+			Response: microtest.Response{
+				Status: 200,
+				Body:   `{"message":"","data":{},"errors":{}}`,
+			},
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		for _, f := range calls {
+			wg.Add(1)
+			go func(f func()) {
+				defer wg.Done()
+				f()
+			}(f)
+		}
+	}
+	wg.Wait()
+
+	received := make(map[string]int)
+	for _, ex := range ms.Exchanges {
+		if ex.Request == nil {
+			t.Errorf("expected every exchange to be transmitted")
+			continue
+		}
+		key := fmt.Sprintf("%s %s?%s", ex.Request.Method, ex.Request.URL.Path, ex.Request.URL.RawQuery)
+		received[key]++
+	}
+	for key := range calls {
+		if received[key] != n {
+			t.Errorf("expected %d exchanges to '%s' got %d", n, key, received[key])
+		}
+	}
+	if len(received) != len(calls) {
+		t.Errorf("expected %d distinct endpoints got %d: %v", len(calls), len(received), received)
+	}
+}
+
+func TestContextError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tt := []struct {
+		name   string
+		ctx    context.Context
+		status int
+		e      dutil.Error
+	}{
+		{
+			name: "context not done",
+			ctx:  context.Background(),
+			e:    nil,
+		},
+		{
+			name:   "context cancelled",
+			ctx:    cancelled,
+			status: 499,
+			e:      dutil.NewErr(499, "context", []string{"context canceled"}),
+		},
+		{
+			name:   "context deadline exceeded",
+			ctx:    expired,
+			status: 504,
+			e:      dutil.NewErr(504, "context", []string{"context deadline exceeded"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := contextError(tc.ctx)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && dutil.Inst(e).Status != tc.status {
+				t.Errorf("expected status %d got %d", tc.status, dutil.Inst(e).Status)
+			}
+		})
+	}
+}
+
+func TestService_send(t *testing.T) {
+	s := NewService("my-test-token")
+	ms := microtest.MockServer(s.serv)
+
+	t.Run("aborted exchange", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		res, e := s.send(ctx, "GET", s.url("/bank", nil), nil, nil)
+		if res != nil {
+			t.Errorf("expected no response got %v", res)
+		}
+		if dutil.Inst(e).Status != 499 {
+			t.Errorf("expected status 499 got %d", dutil.Inst(e).Status)
+		}
+		if len(ms.Exchanges) != 0 {
+			t.Errorf("expected no exchanges got %d", len(ms.Exchanges))
+		}
+	})
+
+	t.Run("headers are not shared", func(t *testing.T) {
+		ms.Append(&microtest.Exchange{
+			Response: microtest.Response{Status: 200, Body: `{}`},
+		})
+		_, e := s.send(context.Background(), "GET", s.url("/bank", nil), nil, nil)
+		if e != nil {
+			t.Errorf("unexpected error %v", e)
+		}
+		token := ms.Exchanges[0].Request.Header.Get("X-User-Token")
+		if token != "my-test-token" {
+			t.Errorf("expected token %s got %s", "my-test-token", token)
+		}
+		ms.Exchanges[0].Request.Header.Set("X-User-Token", "changed")
+		if s.serv.Header.Get("X-User-Token") != "my-test-token" {
+			t.Errorf("expected service header to be unchanged")
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package bankserv
+
+import (
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// Statement is a bank statement of a single bank account which is read from
+// a statement file, such as an OFX download or an MT940 file.
+type Statement struct {
+	// Reference is the reference of the statement, such as the :20: of an
+	// MT940 statement, and Number is the sequence number of the statement.
+	Reference string
+	Number    string
+	// AccountNumber and IBAN are the number of the bank account in the
+	// statement, the IBAN is only set if the statement has the IBAN of the
+	// bank account.
+	AccountNumber string
+	IBAN          string
+	// AccountUUID is the UUID of the bank account of the statement, if the
+	// bank account is matched with one of the bank accounts passed to the
+	// parser. The transactions of the statement have the same AccountUUID.
+	AccountUUID uuid.UUID
+	Currency    string
+	// Opening is the booked balance at the start of the statement, Closing is
+	// the booked balance at the end of the statement, the LEDGERBAL of an OFX
+	// statement, and Available is the balance available at the end of the
+	// statement, the AVAILBAL of an OFX statement. A balance which is not in
+	// the statement is nil.
+	Opening      *StatementBalance
+	Closing      *StatementBalance
+	Available    *StatementBalance
+	Transactions Transactions
+}
+
+// StatementBalance is a balance of a bank statement at a date.
+type StatementBalance struct {
+	Amount Money
+	Date   time.Time
+}
+
+// matchBankAccount sets the AccountUUID of the statement and its transactions
+// to the UUID of the bank account with the IBAN or the account number of the
+// statement. Account numbers are compared without spaces and dashes.
+func (st *Statement) matchBankAccount(xba BankAccounts) {
+	number := normaliseAccountNumber(st.AccountNumber)
+	iban := normaliseAccountNumber(st.IBAN)
+	if number == "" && iban == "" {
+		return
+	}
+	for _, ba := range xba {
+		if iban != "" && normaliseAccountNumber(ba.IBAN) == iban {
+			st.AccountUUID = ba.UUID
+			break
+		}
+		if number != "" && normaliseAccountNumber(ba.AccountNumber) == number {
+			st.AccountUUID = ba.UUID
+			break
+		}
+	}
+	for i := range st.Transactions {
+		st.Transactions[i].AccountUUID = st.AccountUUID
+	}
+}
+
+// normaliseAccountNumber returns the account number in upper case without
+// spaces and dashes.
+func normaliseAccountNumber(s string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(s)))
+}
+
+// ExcludeImported returns the transactions of xt which are not already in
+// existing, based on the ExternalID of the transactions of the same bank
+// account, such that importing a statement twice does not create the same
+// transactions twice. Transactions without an ExternalID are always
+// returned.
+func ExcludeImported(xt, existing Transactions) Transactions {
+	type id struct {
+		accountUUID uuid.UUID
+		externalID  string
+	}
+	seen := make(map[id]bool, len(existing))
+	for _, t := range existing {
+		if t.ExternalID != "" {
+			seen[id{t.AccountUUID, t.ExternalID}] = true
+		}
+	}
+	x := make(Transactions, 0, len(xt))
+	for _, t := range xt {
+		if t.ExternalID != "" {
+			k := id{t.AccountUUID, t.ExternalID}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		x = append(x, t)
+	}
+	return x
+}
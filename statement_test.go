@@ -0,0 +1,57 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestExcludeImported(t *testing.T) {
+	other := uuid.MustParse("7a4c51ab-c87b-4fd3-8d84-b5d2e3e7b0b1")
+	imported := func(account uuid.UUID, externalID string) Transaction {
+		t := txn("2022-06-18T15:26:22Z", -23619)
+		t.AccountUUID = account
+		t.ExternalID = externalID
+		return t
+	}
+	a := imported(superspar.AccountUUID, "1")
+	b := imported(superspar.AccountUUID, "2")
+	c := imported(other, "1")
+	manual := imported(superspar.AccountUUID, "")
+
+	tt := []struct {
+		name     string
+		xt       Transactions
+		existing Transactions
+		o        Transactions
+	}{
+		{
+			name:     "nothing imported before",
+			xt:       Transactions{a, b},
+			existing: Transactions{},
+			o:        Transactions{a, b},
+		},
+		{
+			name:     "imported before",
+			xt:       Transactions{a, b, c},
+			existing: Transactions{a},
+			o:        Transactions{b, c},
+		},
+		{
+			name:     "duplicates in the statement and without an external id",
+			xt:       Transactions{a, a, manual, manual},
+			existing: Transactions{manual},
+			o:        Transactions{a, manual, manual},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			o := ExcludeImported(tc.xt, tc.existing)
+			if !EqualTransactions(tc.o, o) {
+				t.Errorf("expected %v got %v", tc.o, o)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package bankserv
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/url"
+)
+
+// GetUserTags gets the tag catalogue of a specific user based on the user's
+// UUID passed to the function and returns a slice of Tag. If an error occurs
+// such as the user not found then an empty slice is returned and an error.
+func (s *Service) GetUserTags(UUID uuid.UUID) (Tags, dutil.Error) {
+	return s.GetUserTagsContext(context.Background(), UUID)
+}
+
+// GetUserTagsContext is the same as GetUserTags, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) GetUserTagsContext(ctx context.Context, UUID uuid.UUID) (Tags, dutil.Error) {
+	xt := Tags{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/tag/user/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "tags",
+		data:   &xt,
+	})
+	if e != nil {
+		return Tags{}, e
+	}
+	return xt, nil
+}
+
+// GetOrganisationTags gets the tag catalogue of a specific organisation based
+// on the organisation's UUID and returns a slice of Tag. If an error occurs an
+// error is returned.
+func (s *Service) GetOrganisationTags(UUID uuid.UUID) (Tags, dutil.Error) {
+	return s.GetOrganisationTagsContext(context.Background(), UUID)
+}
+
+// GetOrganisationTagsContext is the same as GetOrganisationTags, the context
+// passed to the function is used to cancel the exchange with the bank-service.
+func (s *Service) GetOrganisationTagsContext(ctx context.Context, UUID uuid.UUID) (Tags, dutil.Error) {
+	xt := Tags{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/tag/organisation/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "tags",
+		data:   &xt,
+	})
+	if e != nil {
+		return Tags{}, e
+	}
+	return xt, nil
+}
+
+// CreateTag creates a new tag in the catalogue of either the user or the
+// organisation based on which UUID is provided, and returns the tag.
+func (s *Service) CreateTag(t Tag) (Tag, dutil.Error) {
+	return s.CreateTagContext(context.Background(), t)
+}
+
+// CreateTagContext is the same as CreateTag, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) CreateTagContext(ctx context.Context, t Tag) (Tag, dutil.Error) {
+	if e := t.Validate(); e != nil {
+		return Tag{}, e
+	}
+	tag := Tag{}
+	e := s.do(ctx, exchange{
+		method:  "POST",
+		path:    "/tag",
+		payload: t,
+		status:  201,
+		key:     "tag",
+		data:    &tag,
+	})
+	if e != nil {
+		return Tag{}, e
+	}
+	return tag, nil
+}
+
+// UpdateTag updates a specific tag's data. A tag is renamed by updating the
+// Tag field and deactivated by updating the Active field.
+func (s *Service) UpdateTag(t Tag) (Tag, dutil.Error) {
+	return s.UpdateTagContext(context.Background(), t)
+}
+
+// UpdateTagContext is the same as UpdateTag, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) UpdateTagContext(ctx context.Context, t Tag) (Tag, dutil.Error) {
+	if e := validateUpdate(t.UUID, t); e != nil {
+		return Tag{}, e
+	}
+	tag := Tag{}
+	e := s.do(ctx, exchange{
+		method:  "PUT",
+		path:    "/tag/-",
+		payload: t,
+		status:  200,
+		key:     "tag",
+		data:    &tag,
+	})
+	if e != nil {
+		return Tag{}, e
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes a specific tag from the catalogue. It only returns an
+// error if an error has occurred.
+func (s *Service) DeleteTag(UUID uuid.UUID) dutil.Error {
+	return s.DeleteTagContext(context.Background(), UUID)
+}
+
+// DeleteTagContext is the same as DeleteTag, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) DeleteTagContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	return s.do(ctx, exchange{
+		method: "DELETE",
+		path:   "/tag/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+	})
+}
+
+// MergeTags merges the tags with the UUIDs in xUUID into the tag with the
+// UUID passed to the function. Every item tagged with a merged tag is tagged
+// with the tag instead, and the merged tags are removed from the catalogue.
+// The tag that the tags were merged into is returned.
+func (s *Service) MergeTags(UUID uuid.UUID, xUUID []uuid.UUID) (Tag, dutil.Error) {
+	return s.MergeTagsContext(context.Background(), UUID, xUUID)
+}
+
+// MergeTagsContext is the same as MergeTags, the context passed to the
+// function is used to cancel the exchange with the bank-service.
+func (s *Service) MergeTagsContext(ctx context.Context, UUID uuid.UUID, xUUID []uuid.UUID) (Tag, dutil.Error) {
+	payload := struct {
+		UUID  uuid.UUID   `json:"uuid"`
+		UUIDs []uuid.UUID `json:"merge_uuids"`
+	}{
+		UUID:  UUID,
+		UUIDs: xUUID,
+	}
+	tag := Tag{}
+	e := s.do(ctx, exchange{
+		method:  "POST",
+		path:    "/tag/merge",
+		payload: payload,
+		status:  200,
+		key:     "tag",
+		data:    &tag,
+	})
+	if e != nil {
+		return Tag{}, e
+	}
+	return tag, nil
+}
@@ -0,0 +1,397 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+var groceries = Tag{
+	UUID:       uuid.MustParse("5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90"),
+	UserUUID:   uuid.MustParse("ef50ad5f-539a-454d-bb49-c2e3123eaba8"),
+	Tag:        "groceries",
+	Active:     true,
+	CreateDate: timeMustParse("2022-06-20T08:00:00Z"),
+	UpdateDate: timeMustParse("2022-06-20T08:00:00Z"),
+}
+
+const groceriesJSON = `{"uuid":"5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90","user_uuid":"ef50ad5f-539a-454d-bb49-c2e3123eaba8","organisation_uuid":null,"tag":"groceries","active":true,"create_date":"2022-06-20T08:00:00Z","update_date":"2022-06-20T08:00:00Z"}`
+
+func TestService_GetUserTags(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		tags     Tags
+		e        dutil.Error
+	}{
+		{
+			name: "user not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"user":["not found"]}}`,
+				},
+			},
+			tags: Tags{},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"user": {"not found"},
+				},
+			},
+		},
+		{
+			name: "user has no tags",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"user tags found","data":{"tags":[]},"errors":{}}`,
+				},
+			},
+			tags: Tags{},
+			e:    nil,
+		},
+		{
+			name: "user has tags",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"user tags found","data":{"tags":[` + groceriesJSON + `]},"errors":{}}`,
+				},
+			},
+			tags: Tags{groceries},
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			xt, e := s.GetUserTags(groceries.UserUUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualTags(tc.tags, xt) {
+				t.Errorf("expected tags %v got %v", tc.tags, xt)
+			}
+			if tc.exchange.Request.URL.Path != "/tag/user/-" {
+				t.Errorf("expected path %s got %s", "/tag/user/-", tc.exchange.Request.URL.Path)
+			}
+		})
+	}
+}
+
+func TestService_GetOrganisationTags(t *testing.T) {
+	organisationTag := Tag{
+		UUID:             uuid.MustParse("5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90"),
+		OrganisationUUID: uuid.MustParse("e4bd194d-41e7-4f27-a4a8-161685a9b8b8"),
+		Tag:              "travel",
+		Active:           true,
+	}
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		tags     Tags
+		e        dutil.Error
+	}{
+		{
+			name: "permission required",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 403,
+					Body:   `{"message":"Forbidden: Unable to process request","data":{},"errors":{"permission":["Please ensure you have permission"]}}`,
+				},
+			},
+			tags: Tags{},
+			e: &dutil.Err{
+				Status: 403,
+				Errors: map[string][]string{
+					"permission": {"Please ensure you have permission"},
+				},
+			},
+		},
+		{
+			name: "organisation has tags",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"organisation tags found","data":{"tags":[{"uuid":"5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90","user_uuid":null,"organisation_uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","tag":"travel","active":true}]},"errors":{}}`,
+				},
+			},
+			tags: Tags{organisationTag},
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			xt, e := s.GetOrganisationTags(organisationTag.OrganisationUUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualTags(tc.tags, xt) {
+				t.Errorf("expected tags %v got %v", tc.tags, xt)
+			}
+			if tc.exchange.Request.URL.Path != "/tag/organisation/-" {
+				t.Errorf("expected path %s got %s", "/tag/organisation/-", tc.exchange.Request.URL.Path)
+			}
+		})
+	}
+}
+
+func TestService_CreateTag(t *testing.T) {
+	tt := []struct {
+		name     string
+		tag      Tag
+		exchange *microtest.Exchange
+		ETag     Tag
+		e        dutil.Error
+	}{
+		{
+			name: "tag exists",
+			tag:  Tag{UserUUID: groceries.UserUUID, Tag: "groceries"},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 409,
+					Body:   `{"message":"Conflict: Unable to process request","data":{},"errors":{"tag":["already exists"]}}`,
+				},
+			},
+			ETag: Tag{},
+			e: &dutil.Err{
+				Status: 409,
+				Errors: map[string][]string{
+					"tag": {"already exists"},
+				},
+			},
+		},
+		{
+			name: "create tag",
+			tag:  Tag{UserUUID: groceries.UserUUID, Tag: "groceries"},
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 201,
+					Body:   `{"message":"tag created","data":{"tag":` + groceriesJSON + `},"errors":{}}`,
+				},
+			},
+			ETag: groceries,
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			tag, e := s.CreateTag(tc.tag)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if tag != tc.ETag {
+				t.Errorf("expected tag %v got %v", tc.ETag, tag)
+			}
+		})
+	}
+}
+
+func TestService_UpdateTag(t *testing.T) {
+	renamed := groceries
+	renamed.Tag = "food"
+	deactivated := groceries
+	deactivated.Active = false
+
+	tt := []struct {
+		name     string
+		tag      Tag
+		exchange *microtest.Exchange
+		ETag     Tag
+		e        dutil.Error
+	}{
+		{
+			name: "bad request",
+			tag:  Tag{},
+			ETag: Tag{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"uuid": {"required field"},
+				},
+			},
+		},
+		{
+			name: "rename tag",
+			tag:  renamed,
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"tag updated","data":{"tag":{"uuid":"5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90","user_uuid":"ef50ad5f-539a-454d-bb49-c2e3123eaba8","organisation_uuid":null,"tag":"food","active":true,"create_date":"2022-06-20T08:00:00Z","update_date":"2022-06-20T08:00:00Z"}},"errors":{}}`,
+				},
+			},
+			ETag: renamed,
+			e:    nil,
+		},
+		{
+			name: "deactivate tag",
+			tag:  deactivated,
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"tag updated","data":{"tag":{"uuid":"5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90","user_uuid":"ef50ad5f-539a-454d-bb49-c2e3123eaba8","organisation_uuid":null,"tag":"groceries","active":false,"create_date":"2022-06-20T08:00:00Z","update_date":"2022-06-20T08:00:00Z"}},"errors":{}}`,
+				},
+			},
+			ETag: deactivated,
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			if tc.exchange != nil {
+				ms.Append(tc.exchange)
+			}
+
+			tag, e := s.UpdateTag(tc.tag)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if tag != tc.ETag {
+				t.Errorf("expected tag %v got %v", tc.ETag, tag)
+			}
+		})
+	}
+}
+
+func TestService_DeleteTag(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		e        dutil.Error
+	}{
+		{
+			name: "tag not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"tag":["not found"]}}`,
+				},
+			},
+			e: &dutil.Err{
+				Status: 404,
+				Errors: map[string][]string{
+					"tag": {"not found"},
+				},
+			},
+		},
+		{
+			name: "tag deleted",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"tag deleted","data":{},"errors":{}}`,
+				},
+			},
+			e: nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			e := s.DeleteTag(groceries.UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			q := tc.exchange.Request.URL.Query().Get("uuid")
+			if q != groceries.UUID.String() {
+				t.Errorf("expected query uuid %s got %s", groceries.UUID.String(), q)
+			}
+		})
+	}
+}
+
+func TestService_MergeTags(t *testing.T) {
+	merged := []uuid.UUID{
+		uuid.MustParse("8a1f5c2e-3d4b-4e6f-9a7b-0c1d2e3f4a5b"),
+		uuid.MustParse("9b2e6d3f-4e5c-4f70-8b8c-1d2e3f4a5b6c"),
+	}
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		ETag     Tag
+		e        dutil.Error
+	}{
+		{
+			name: "tags from different catalogues",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 400,
+					Body:   `{"message":"BadRequest: Unable to process request","data":{},"errors":{"merge_uuids":["tags must be in the same catalogue"]}}`,
+				},
+			},
+			ETag: Tag{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"merge_uuids": {"tags must be in the same catalogue"},
+				},
+			},
+		},
+		{
+			name: "tags merged",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"tags merged","data":{"tag":` + groceriesJSON + `},"errors":{}}`,
+				},
+			},
+			ETag: groceries,
+			e:    nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			tag, e := s.MergeTags(groceries.UUID, merged)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if tag != tc.ETag {
+				t.Errorf("expected tag %v got %v", tc.ETag, tag)
+			}
+			if tc.exchange.Request.URL.Path != "/tag/merge" {
+				t.Errorf("expected path %s got %s", "/tag/merge", tc.exchange.Request.URL.Path)
+			}
+		})
+	}
+}
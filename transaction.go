@@ -1,158 +1,165 @@
 package bankserv
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
+	"net/http"
 	"net/url"
 )
 
+// GetTransaction gets a specific transaction with its items based on the
+// transaction's UUID passed to the function. If the transaction does not exist
+// an error with the status 404 is returned.
+func (s *Service) GetTransaction(UUID uuid.UUID) (Transaction, dutil.Error) {
+	return s.GetTransactionContext(context.Background(), UUID)
+}
+
+// GetTransactionContext is the same as GetTransaction, the context passed to
+// the function is used to cancel the exchange with the bank-service.
+func (s *Service) GetTransactionContext(ctx context.Context, UUID uuid.UUID) (Transaction, dutil.Error) {
+	txn := Transaction{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/transaction/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "transaction",
+		data:   &txn,
+	})
+	if e != nil {
+		return Transaction{}, e
+	}
+	if txn.UUID == uuid.Nil {
+		return Transaction{}, notFound("transaction")
+	}
+	return txn, nil
+}
+
 // GetBankAccountTransactions gets all the transactions for a specific bank
 // account based on the bank account's UUID passed to the function and returns
 // a slice of Transaction. If an error occurs the error will not be nil. If the
 // bank account has no transactions an empty slice will be returned.
 func (s *Service) GetBankAccountTransactions(UUID uuid.UUID) (Transactions, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/transaction/bank-account/-"
-	// set query string
-	qs := url.Values{"uuid": {UUID.String()}}
-	s.serv.URL.RawQuery = qs.Encode()
-	// do request
-	r, e := s.serv.NewRequest("GET", s.serv.URL.String(), nil, nil)
-	if e != nil {
-		return Transactions{}, e
-	}
-	type Data struct {
-		Transactions `json:"transactions"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors dutil.Errors `json:"errors"`
-	}{}
-	// decode the response
-	_, e = s.serv.Decode(r, &res)
-	if e != nil {
-		return Transactions{}, e
-	}
+	return s.GetBankAccountTransactionsContext(context.Background(), UUID)
+}
 
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
+// GetBankAccountTransactionsContext is the same as GetBankAccountTransactions,
+// the context passed to the function is used to cancel the exchange with the
+// bank-service.
+func (s *Service) GetBankAccountTransactionsContext(ctx context.Context, UUID uuid.UUID) (Transactions, dutil.Error) {
+	xt := Transactions{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/transaction/bank-account/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+		key:    "transactions",
+		data:   &xt,
+	})
+	if e != nil {
 		return Transactions{}, e
 	}
-	return res.Data.Transactions, nil
+	return xt, nil
 }
 
 // CreateTransaction creates a new transaction for a bank account based on the
-// transaction data that is passed to the function.
+// transaction data that is passed to the function. Every create is sent with
+// a new idempotency key, use CreateTransactionContext and WithIdempotencyKey
+// to retry a create safely.
 func (s *Service) CreateTransaction(t Transaction) (Transaction, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/transaction"
-	// marshal payload
-	p, e := dutil.MarshalReader(t)
-	if e != nil {
-		return Transaction{}, e
-	}
-	// do request
-	r, e := s.serv.NewRequest("POST", s.serv.URL.String(), nil, p)
-	if e != nil {
-		return Transaction{}, e
-	}
+	return s.CreateTransactionContext(context.Background(), t)
+}
 
-	type Data struct {
-		Transaction `json:"transaction"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors dutil.Errors `json:"errors"`
-	}{}
-	// decode response
-	_, e = s.serv.Decode(r, &res)
-	if e != nil {
+// CreateTransactionContext is the same as CreateTransaction, the context passed
+// to the function is used to cancel the exchange with the bank-service.
+//
+// If the context carries an idempotency key, see WithIdempotencyKey, a create
+// with the key of a previous successful create returns the original
+// transaction instead of creating a second transaction.
+func (s *Service) CreateTransactionContext(ctx context.Context, t Transaction) (Transaction, dutil.Error) {
+	if e := t.Validate(); e != nil {
 		return Transaction{}, e
 	}
-
-	if r.StatusCode != 201 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
+	v, e := s.idempotent(ctx, "transaction", func(header http.Header) (interface{}, dutil.Error) {
+		txn := Transaction{}
+		e := s.do(ctx, exchange{
+			method:  "POST",
+			path:    "/transaction",
+			header:  header,
+			payload: t,
+			status:  201,
+			key:     "transaction",
+			data:    &txn,
+		})
+		return txn, e
+	})
+	if e != nil {
 		return Transaction{}, e
 	}
 	// return transaction on successful exchange
-	return res.Data.Transaction, nil
+	return v.(Transaction), nil
 }
 
 // UpdateTransaction updates a transaction for a bank account based on the
 // transaction's UUID and transaction data that is passed to the function.
 func (s *Service) UpdateTransaction(t Transaction) (Transaction, dutil.Error) {
-	// set path
-	s.serv.URL.Path = "/transaction/-"
-	// read payload
-	p, e := dutil.MarshalReader(t)
-	if e != nil {
-		return Transaction{}, e
-	}
-
-	// do request
-	r, e := s.serv.NewRequest("PUT", s.serv.URL.String(), nil, p)
+	return s.UpdateTransactionContext(context.Background(), t)
+}
 
-	type Data struct {
-		Transaction `json:"transaction"`
-	}
-	res := struct {
-		Data   `json:"data"`
-		Errors dutil.Errors `json:"errors"`
-	}{}
-	// decode response
-	_, e = s.serv.Decode(r, &res)
-	if e != nil {
+// UpdateTransactionContext is the same as UpdateTransaction, the context passed
+// to the function is used to cancel the exchange with the bank-service.
+func (s *Service) UpdateTransactionContext(ctx context.Context, t Transaction) (Transaction, dutil.Error) {
+	if e := validateUpdate(t.UUID, t); e != nil {
 		return Transaction{}, e
 	}
-
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
+	txn := Transaction{}
+	e := s.do(ctx, exchange{
+		method:  "PUT",
+		path:    "/transaction/-",
+		payload: t,
+		status:  200,
+		key:     "transaction",
+		data:    &txn,
+	})
+	if e != nil {
 		return Transaction{}, e
 	}
-
-	return res.Data.Transaction, nil
+	return txn, nil
 }
 
 // DeleteTransaction deletes a specific transaction from a bank account. It only
 // returns an error if an error has occurred, otherwise it will return nil if
 // transaction has successfully been deleted.
 func (s *Service) DeleteTransaction(UUID uuid.UUID) dutil.Error {
-	// set path
-	s.serv.URL.Path = "/transaction/-"
-	// set query string
-	qs := url.Values{"uuid": {UUID.String()}}
-	s.serv.URL.RawQuery = qs.Encode()
-
-	r, e := s.serv.NewRequest("DELETE", s.serv.URL.String(), nil, nil)
-	if e != nil {
-		return e
-	}
+	return s.DeleteTransactionContext(context.Background(), UUID)
+}
 
-	res := struct {
-		Errors dutil.Errors `json:"errors"`
-	}{}
-	// decode response
-	_, e = s.serv.Decode(r, &res)
-	if e != nil {
-		return e
-	}
+// DeleteTransactionContext is the same as DeleteTransaction, the context passed
+// to the function is used to cancel the exchange with the bank-service.
+func (s *Service) DeleteTransactionContext(ctx context.Context, UUID uuid.UUID) dutil.Error {
+	return s.do(ctx, exchange{
+		method: "DELETE",
+		path:   "/transaction/-",
+		query:  url.Values{"uuid": {UUID.String()}},
+		status: 200,
+	})
+}
 
-	if r.StatusCode != 200 {
-		e := &dutil.Err{
-			Status: r.StatusCode,
-			Errors: res.Errors,
-		}
-		return e
-	}
-	// return on successful response
-	return nil
+// MarshalJSON marshals a transaction without the counterpart_uuid if the
+// transaction is not a transfer.
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	// transaction does not have the MarshalJSON method of Transaction
+	type transaction Transaction
+	x := struct {
+		transaction
+		CounterpartUUID *uuid.UUID `json:"counterpart_uuid,omitempty"`
+	}{
+		transaction: transaction(t),
+	}
+	if t.CounterpartUUID != uuid.Nil {
+		x.CounterpartUUID = &t.CounterpartUUID
+	}
+	return json.Marshal(x)
 }
@@ -0,0 +1,229 @@
+package bankserv
+
+import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBulkConcurrency is the number of transactions which CreateTransactions
+// creates at the same time if the bank-service does not have a batch
+// endpoint and the concurrency of the BulkOptions is not set.
+const DefaultBulkConcurrency = 8
+
+// batchSize is the maximum number of transactions of a single exchange with
+// the batch endpoint of the bank-service.
+const batchSize = 500
+
+// BulkOptions configures how CreateTransactions creates the transactions.
+type BulkOptions struct {
+	// Concurrency is the maximum number of transactions created at the same
+	// time when the transactions are created one by one. If zero
+	// DefaultBulkConcurrency is used.
+	Concurrency int
+	// StopOnError stops creating transactions at the first transaction which
+	// fails. The transactions which are not created because of the stop have
+	// an error of the kind ErrSkipped. By default every transaction is
+	// created, regardless of the transactions which fail.
+	StopOnError bool
+}
+
+// TransactionResult is the result of the create of a single transaction of
+// CreateTransactions. Either the Transaction is the created transaction or
+// the Error is the reason the transaction was not created.
+type TransactionResult struct {
+	Transaction Transaction
+	Error       dutil.Error
+}
+
+// CreateTransactions creates many transactions, for example the transactions
+// of an imported bank statement, see CreateTransactionsContext.
+func (s *Service) CreateTransactions(xt Transactions, opts BulkOptions) ([]TransactionResult, dutil.Error) {
+	return s.CreateTransactionsContext(context.Background(), xt, opts)
+}
+
+// CreateTransactionsContext is the same as CreateTransactions, the context
+// passed to the function is used to cancel the exchanges with the
+// bank-service.
+//
+// The transactions are sent to the batch endpoint of the bank-service in
+// batches of up to 500 transactions. The bank-service creates the
+// transactions of a batch as a whole, if a batch fails every transaction of
+// the batch has the error of the batch. If the bank-service does not have a
+// batch endpoint, the transactions are created one by one with at most
+// opts.Concurrency creates at the same time.
+//
+// A result is returned for every transaction, in the order of the
+// transactions passed to the function. The error returned is the error of
+// the first transaction which was not created, or nil if every transaction
+// was created. Invalid transactions are never sent to the bank-service.
+//
+// If the context carries an idempotency key, see WithIdempotencyKey, every
+// batch and every transaction is created with a key derived from the key.
+func (s *Service) CreateTransactionsContext(ctx context.Context, xt Transactions, opts BulkOptions) ([]TransactionResult, dutil.Error) {
+	// every transaction is skipped until it is sent to the bank-service
+	skipped := newError(dutil.NewErr(424, "skipped", []string{"not created, the bulk create stopped at an error"}))
+	results := make([]TransactionResult, len(xt))
+	// the indexes of the valid transactions, up to the first invalid
+	// transaction if the create stops at an error
+	valid := make([]int, 0, len(xt))
+	stop := false
+	for i, t := range xt {
+		results[i].Error = skipped
+		if stop {
+			continue
+		}
+		if e := t.Validate(); e != nil {
+			results[i].Error = e
+			stop = opts.StopOnError
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if atomic.LoadInt32(&s.noBatch) == 1 || !s.createBatches(ctx, xt, valid, results, opts) {
+		s.createEach(ctx, xt, valid, results, opts)
+	}
+
+	var first dutil.Error
+	for _, r := range results {
+		if r.Error != nil {
+			first = r.Error
+			break
+		}
+	}
+	return results, first
+}
+
+// createBatches creates the transactions at the indexes passed to the
+// function with the batch endpoint of the bank-service and sets the results
+// of the transactions. It returns false, without setting any results, if
+// the bank-service does not have a batch endpoint.
+func (s *Service) createBatches(ctx context.Context, xt Transactions, indexes []int, results []TransactionResult, opts BulkOptions) bool {
+	key, _ := idempotencyKey(ctx)
+	for n := 0; n*batchSize < len(indexes); n++ {
+		end := (n + 1) * batchSize
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		batch := indexes[n*batchSize : end]
+		payload := make(Transactions, len(batch))
+		for j, i := range batch {
+			payload[j] = xt[i]
+		}
+
+		header := http.Header{idempotencyHeader: {fmt.Sprintf("%s-batch-%d", key, n)}}
+		created := make(Transactions, 0, len(batch))
+		e := s.do(ctx, exchange{
+			method: "POST",
+			path:   "/transaction/batch",
+			header: header,
+			payload: map[string]interface{}{
+				"transactions": payload,
+			},
+			status: 201,
+			key:    "transactions",
+			data:   &created,
+		})
+		if e != nil && n == 0 && batchUnavailable(e) {
+			atomic.StoreInt32(&s.noBatch, 1)
+			return false
+		}
+		if e == nil && len(created) != len(batch) {
+			e = newError(dutil.NewErr(500, "transactions", []string{
+				fmt.Sprintf("expected %d transactions got %d", len(batch), len(created)),
+			}))
+		}
+		for j, i := range batch {
+			if e != nil {
+				results[i].Error = e
+				continue
+			}
+			results[i] = TransactionResult{Transaction: created[j]}
+		}
+		if e != nil && opts.StopOnError {
+			break
+		}
+	}
+	return true
+}
+
+// batchUnavailable reports whether the error of the first exchange with the
+// batch endpoint is because the bank-service does not have a batch endpoint,
+// in which case the transactions are created one by one. The batch endpoint
+// is not available if
+//   - the response is a 404 or 405 that is not from the bank-service, such as
+//     the response of a router or gateway in front of the bank-service;
+//   - the bank-service responds 405, the route does not allow a POST;
+//   - the bank-service responds 404 without errors or with only an error keyed
+//     by "route", the route itself is not found.
+//
+// A 404 of the bank-service with the errors of a resource, such as
+// {"bank_account": ["not found"]}, is the error of the batch instead, every
+// transaction would fail the same way if it is created on its own.
+func batchUnavailable(e dutil.Error) bool {
+	err := dutil.Inst(e)
+	if _, ok := err.Errors["response"]; ok {
+		return err.Status == 404 || err.Status == 405
+	}
+	switch err.Status {
+	case 405:
+		return true
+	case 404:
+		for k := range err.Errors {
+			if k != "route" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// createEach creates the transactions at the indexes passed to the function
+// one by one, with at most opts.Concurrency creates at the same time, and
+// sets the results of the transactions. The transactions are started in
+// order, if the create stops at an error no transaction is started after the
+// first error.
+func (s *Service) createEach(ctx context.Context, xt Transactions, indexes []int, results []TransactionResult, opts BulkOptions) {
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultBulkConcurrency
+	}
+	key, supplied := idempotencyKey(ctx)
+
+	var stopped int32
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if atomic.LoadInt32(&stopped) == 1 {
+					continue
+				}
+				c := ctx
+				if supplied {
+					c = WithIdempotencyKey(ctx, fmt.Sprintf("%s-%d", key, i))
+				}
+				t, e := s.CreateTransactionContext(c, xt[i])
+				results[i] = TransactionResult{Transaction: t, Error: e}
+				if e != nil && opts.StopOnError {
+					atomic.StoreInt32(&stopped, 1)
+				}
+			}
+		}()
+	}
+	for _, i := range indexes {
+		if atomic.LoadInt32(&stopped) == 1 {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,293 @@
+package bankserv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// bulkTransaction returns a valid transaction of the bulk tests with the
+// description passed to the function.
+func bulkTransaction(description string) Transaction {
+	t := superspar
+	t.Description = description
+	return t
+}
+
+// createdJSON returns the JSON of a created transaction with the
+// description passed to the function.
+func createdJSON(n int, description string) string {
+	return fmt.Sprintf(`{"uuid":"00000000-0000-4000-8000-%012d","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","date":"2022-06-18T15:26:22Z","description":"%s","items":[],"active":true}`, n, description)
+}
+
+func createdUUID(n int) uuid.UUID {
+	return uuid.MustParse(fmt.Sprintf("00000000-0000-4000-8000-%012d", n))
+}
+
+func TestService_CreateTransactions(t *testing.T) {
+	invalid := Transaction{Description: "invalid"}
+	notFound := microtest.Response{Status: 404, Body: "404 page not found"}
+	created := func(n int, description string) microtest.Response {
+		return microtest.Response{
+			Status: 201,
+			Body:   `{"message":"transaction created","data":{"transaction":` + createdJSON(n, description) + `},"errors":{}}`,
+		}
+	}
+	routeNotFound := microtest.Response{
+		Status: 404,
+		Body:   `{"message":"NotFound: Unable to find route","data":{},"errors":{"route":["not found"]}}`,
+	}
+	methodNotAllowed := microtest.Response{
+		Status: 405,
+		Body:   `{"message":"MethodNotAllowed","data":{},"errors":{"method":["not allowed"]}}`,
+	}
+	bankAccountNotFound := microtest.Response{
+		Status: 404,
+		Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"bank_account":["not found"]}}`,
+	}
+	failed := microtest.Response{
+		Status: 409,
+		Body:   `{"message":"conflict","data":{},"errors":{"transaction":["duplicate transaction"]}}`,
+	}
+
+	tt := []struct {
+		name         string
+		transactions Transactions
+		opts         BulkOptions
+		responses    []microtest.Response
+		// paths are the paths of the exchanges expected
+		paths []string
+		// results are the UUIDs of the created transactions or the kind of
+		// the error of each transaction
+		results []interface{}
+	}{
+		{
+			name:         "batch endpoint",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b")},
+			responses: []microtest.Response{
+				{
+					Status: 201,
+					Body:   `{"message":"transactions created","data":{"transactions":[` + createdJSON(1, "a") + `,` + createdJSON(2, "b") + `]},"errors":{}}`,
+				},
+			},
+			paths:   []string{"/transaction/batch"},
+			results: []interface{}{createdUUID(1), createdUUID(2)},
+		},
+		{
+			name:         "batch endpoint with an invalid transaction",
+			transactions: Transactions{bulkTransaction("a"), invalid, bulkTransaction("b")},
+			responses: []microtest.Response{
+				{
+					Status: 201,
+					Body:   `{"message":"transactions created","data":{"transactions":[` + createdJSON(1, "a") + `,` + createdJSON(2, "b") + `]},"errors":{}}`,
+				},
+			},
+			paths:   []string{"/transaction/batch"},
+			results: []interface{}{createdUUID(1), ErrValidation, createdUUID(2)},
+		},
+		{
+			name:         "stop at an invalid transaction",
+			transactions: Transactions{bulkTransaction("a"), invalid, bulkTransaction("b")},
+			opts:         BulkOptions{StopOnError: true},
+			responses: []microtest.Response{
+				{
+					Status: 201,
+					Body:   `{"message":"transactions created","data":{"transactions":[` + createdJSON(1, "a") + `]},"errors":{}}`,
+				},
+			},
+			paths:   []string{"/transaction/batch"},
+			results: []interface{}{createdUUID(1), ErrValidation, ErrSkipped},
+		},
+		{
+			name:         "failed batch",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b")},
+			responses:    []microtest.Response{failed},
+			paths:        []string{"/transaction/batch"},
+			results:      []interface{}{ErrConflict, ErrConflict},
+		},
+		{
+			name:         "without batch endpoint",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b")},
+			opts:         BulkOptions{Concurrency: 1},
+			responses:    []microtest.Response{notFound, created(1, "a"), created(2, "b")},
+			paths:        []string{"/transaction/batch", "/transaction", "/transaction"},
+			results:      []interface{}{createdUUID(1), createdUUID(2)},
+		},
+		{
+			name:         "bank-service without batch route",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b")},
+			opts:         BulkOptions{Concurrency: 1},
+			responses:    []microtest.Response{routeNotFound, created(1, "a"), created(2, "b")},
+			paths:        []string{"/transaction/batch", "/transaction", "/transaction"},
+			results:      []interface{}{createdUUID(1), createdUUID(2)},
+		},
+		{
+			name:         "bank-service batch route without POST",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b")},
+			opts:         BulkOptions{Concurrency: 1},
+			responses:    []microtest.Response{methodNotAllowed, created(1, "a"), created(2, "b")},
+			paths:        []string{"/transaction/batch", "/transaction", "/transaction"},
+			results:      []interface{}{createdUUID(1), createdUUID(2)},
+		},
+		{
+			name:         "resource not found in batch",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b")},
+			opts:         BulkOptions{Concurrency: 1},
+			responses:    []microtest.Response{bankAccountNotFound},
+			paths:        []string{"/transaction/batch"},
+			results:      []interface{}{ErrNotFound, ErrNotFound},
+		},
+		{
+			name:         "continue after an error",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b"), bulkTransaction("c")},
+			opts:         BulkOptions{Concurrency: 1},
+			responses:    []microtest.Response{notFound, created(1, "a"), failed, created(3, "c")},
+			paths:        []string{"/transaction/batch", "/transaction", "/transaction", "/transaction"},
+			results:      []interface{}{createdUUID(1), ErrConflict, createdUUID(3)},
+		},
+		{
+			name:         "stop at an error",
+			transactions: Transactions{bulkTransaction("a"), bulkTransaction("b"), bulkTransaction("c")},
+			opts:         BulkOptions{Concurrency: 1, StopOnError: true},
+			responses:    []microtest.Response{notFound, created(1, "a"), failed},
+			paths:        []string{"/transaction/batch", "/transaction", "/transaction"},
+			results:      []interface{}{createdUUID(1), ErrConflict, ErrSkipped},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := NewService("")
+			ms := microtest.MockServer(s.serv)
+			xx := make([]*microtest.Exchange, len(tc.responses))
+			for j, res := range tc.responses {
+				xx[j] = &microtest.Exchange{Response: res}
+				ms.Append(xx[j])
+			}
+
+			xr, e := s.CreateTransactions(tc.transactions, tc.opts)
+			if len(xr) != len(tc.transactions) {
+				t.Fatalf("expected %d results got %d", len(tc.transactions), len(xr))
+			}
+			for j, x := range xx {
+				if x.Request == nil || x.Request.URL.Path != tc.paths[j] {
+					t.Errorf("expected exchange %d with %s", j, tc.paths[j])
+				}
+			}
+			var first dutil.Error
+			for j, r := range xr {
+				switch want := tc.results[j].(type) {
+				case uuid.UUID:
+					if r.Error != nil || r.Transaction.UUID != want {
+						t.Errorf("expected transaction %d to be created as %s got %v %v", j, want, r.Transaction.UUID, r.Error)
+					}
+				case error:
+					if !errors.Is(r.Error, want) {
+						t.Errorf("expected transaction %d to fail with %v got %v", j, want, r.Error)
+					}
+					if first == nil {
+						first = r.Error
+					}
+				}
+			}
+			if !dutil.ErrorEqual(first, e) {
+				t.Errorf("expected the error of the first failed transaction %v got %v", first, e)
+			}
+		})
+	}
+}
+
+func TestService_CreateTransactions_noBatch(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	xx := []*microtest.Exchange{
+		{Response: microtest.Response{Status: 404, Body: "404 page not found"}},
+		{Response: microtest.Response{Status: 201, Body: `{"message":"","data":{"transaction":` + createdJSON(1, "a") + `},"errors":{}}`}},
+		{Response: microtest.Response{Status: 201, Body: `{"message":"","data":{"transaction":` + createdJSON(2, "b") + `},"errors":{}}`}},
+	}
+	for _, x := range xx {
+		ms.Append(x)
+	}
+
+	ctx := WithIdempotencyKey(context.Background(), "statement-1")
+	_, _ = s.CreateTransactionsContext(ctx, Transactions{bulkTransaction("a")}, BulkOptions{})
+	// the batch endpoint is not tried again
+	ctx = WithIdempotencyKey(context.Background(), "statement-2")
+	_, _ = s.CreateTransactionsContext(ctx, Transactions{bulkTransaction("b")}, BulkOptions{})
+	for j, path := range []string{"/transaction/batch", "/transaction", "/transaction"} {
+		if xx[j].Request == nil || xx[j].Request.URL.Path != path {
+			t.Errorf("expected exchange %d with %s", j, path)
+		}
+	}
+	for j, key := range []string{"statement-1-batch-0", "statement-1-0", "statement-2-0"} {
+		if got := xx[j].Request.Header.Get("Idempotency-Key"); got != key {
+			t.Errorf("expected idempotency key %s got %s", key, got)
+		}
+	}
+}
+
+func TestService_CreateTransactions_concurrency(t *testing.T) {
+	s := NewService("")
+	atomic.StoreInt32(&s.noBatch, 1)
+	ms := microtest.MockServer(s.serv)
+	n := 12
+	xt := make(Transactions, n)
+	for i := range xt {
+		xt[i] = bulkTransaction(fmt.Sprintf("%d", i))
+		ms.Append(&microtest.Exchange{
+			Response: microtest.Response{
+				Status: 201,
+				Body:   `{"message":"","data":{"transaction":` + createdJSON(i+1, "") + `},"errors":{}}`,
+			},
+		})
+	}
+
+	// count the creates in flight, the mock server itself is not safe for
+	// concurrent use
+	var inFlight, most int32
+	var mu sync.Mutex
+	h := ms.Server.Config.Handler
+	ms.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if c <= m || atomic.CompareAndSwapInt32(&most, m, c) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		mu.Lock()
+		defer mu.Unlock()
+		h.ServeHTTP(w, r)
+	})
+
+	xr, e := s.CreateTransactions(xt, BulkOptions{Concurrency: 3})
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	if most > 3 || most < 2 {
+		t.Errorf("expected at most 3 creates at the same time got %d", most)
+	}
+	// the responses are in the order of the exchanges, which is not the order
+	// of the transactions, every transaction has a distinct result
+	seen := make(map[uuid.UUID]bool)
+	for _, r := range xr {
+		if r.Error != nil || seen[r.Transaction.UUID] {
+			t.Errorf("expected a distinct created transaction got %v %v", r.Transaction.UUID, r.Error)
+		}
+		seen[r.Transaction.UUID] = true
+	}
+	if len(seen) != n {
+		t.Errorf("expected %d transactions got %d", n, len(seen))
+	}
+}
@@ -0,0 +1,214 @@
+package bankserv
+
+import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SortOrder is the order in which transactions are returned.
+type SortOrder string
+
+const (
+	// SortDateAsc sorts transactions from the oldest to the newest date.
+	SortDateAsc SortOrder = "date"
+	// SortDateDesc sorts transactions from the newest to the oldest date.
+	SortDateDesc SortOrder = "-date"
+)
+
+// TransactionQuery filters and pages the transactions of a bank account. The
+// zero value of a field does not filter the transactions, therefore, the zero
+// TransactionQuery returns the first page of all the transactions.
+type TransactionQuery struct {
+	// From and To are the inclusive date range of the transactions.
+	From time.Time
+	To   time.Time
+	// MinAmount and MaxAmount are the inclusive range of the net amount of
+	// the transactions.
+	MinAmount *Money
+	MaxAmount *Money
+	// Search searches the description of the transactions.
+	Search string
+	// TagUUIDs only returns transactions with items tagged with any of the
+	// tags.
+	TagUUIDs []uuid.UUID
+	// Active only returns the active or inactive transactions.
+	Active *bool
+	Sort   SortOrder
+	// PageSize is the maximum number of transactions in a page, if zero the
+	// bank-service's default page size is used.
+	PageSize int
+	// Cursor is the cursor of the page to get, it is the NextCursor of the
+	// previous TransactionPage.
+	Cursor string
+}
+
+// values encodes the query to the query string of the bank-service.
+func (q TransactionQuery) values() url.Values {
+	qs := url.Values{}
+	if !q.From.IsZero() {
+		qs.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		qs.Set("to", q.To.Format(time.RFC3339))
+	}
+	if q.MinAmount != nil {
+		qs.Set("min_amount", q.MinAmount.Decimal())
+	}
+	if q.MaxAmount != nil {
+		qs.Set("max_amount", q.MaxAmount.Decimal())
+	}
+	if q.Search != "" {
+		qs.Set("search", q.Search)
+	}
+	for _, UUID := range q.TagUUIDs {
+		qs.Add("tag_uuid", UUID.String())
+	}
+	if q.Active != nil {
+		qs.Set("active", strconv.FormatBool(*q.Active))
+	}
+	if q.Sort != "" {
+		qs.Set("sort", string(q.Sort))
+	}
+	if q.PageSize > 0 {
+		qs.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	if q.Cursor != "" {
+		qs.Set("cursor", q.Cursor)
+	}
+	return qs
+}
+
+// TransactionPage is a single page of transactions. If NextCursor is empty
+// the page is the last page.
+type TransactionPage struct {
+	Transactions Transactions `json:"transactions"`
+	NextCursor   string       `json:"next_cursor"`
+}
+
+// GetBankAccountTransactionPage gets a single page of the transactions of a
+// specific bank account which match the query passed to the function.
+func (s *Service) GetBankAccountTransactionPage(UUID uuid.UUID, q TransactionQuery) (TransactionPage, dutil.Error) {
+	return s.GetBankAccountTransactionPageContext(context.Background(), UUID, q)
+}
+
+// GetBankAccountTransactionPageContext is the same as
+// GetBankAccountTransactionPage, the context passed to the function is used
+// to cancel the exchange with the bank-service.
+func (s *Service) GetBankAccountTransactionPageContext(ctx context.Context, UUID uuid.UUID, q TransactionQuery) (TransactionPage, dutil.Error) {
+	qs := q.values()
+	qs.Set("uuid", UUID.String())
+
+	p := TransactionPage{}
+	e := s.do(ctx, exchange{
+		method: "GET",
+		path:   "/transaction/bank-account/-",
+		query:  qs,
+		status: 200,
+		data:   &p,
+	})
+	if e != nil {
+		return TransactionPage{}, e
+	}
+	if p.Transactions == nil {
+		p.Transactions = Transactions{}
+	}
+	return p, nil
+}
+
+// TransactionIterator walks through the transactions of a bank account one
+// page at a time. A page is only requested from the bank-service once all
+// the transactions of the previous page have been walked through.
+//
+//	it := s.IterateBankAccountTransactions(ctx, UUID, TransactionQuery{})
+//	for it.Next() {
+//		t := it.Transaction()
+//		...
+//	}
+//	if e := it.Err(); e != nil {
+//		...
+//	}
+type TransactionIterator struct {
+	s           *Service
+	ctx         context.Context
+	accountUUID uuid.UUID
+	q           TransactionQuery
+	page        Transactions
+	i           int
+	// last is set once the last page has been requested
+	last bool
+	// seen are the cursors of the pages which have been requested
+	seen map[string]bool
+	e    dutil.Error
+}
+
+// IterateBankAccountTransactions returns a TransactionIterator over the
+// transactions of a specific bank account which match the query passed to the
+// function. The iterator starts at the query's Cursor.
+func (s *Service) IterateBankAccountTransactions(ctx context.Context, UUID uuid.UUID, q TransactionQuery) *TransactionIterator {
+	return &TransactionIterator{
+		s:           s,
+		ctx:         ctx,
+		accountUUID: UUID,
+		q:           q,
+		i:           -1,
+		seen:        map[string]bool{},
+	}
+}
+
+// Next advances the iterator to the next transaction, which is then available
+// from Transaction. Next returns false when there are no more transactions or
+// an error has occurred, then Err returns the error. A next cursor of a page
+// which has already been requested, such as the cursor of the page itself or
+// of a cycle of pages, is an error with the key "cursor", rather than pages
+// which are requested again and again. The transactions of the page with
+// that next cursor are not returned.
+func (it *TransactionIterator) Next() bool {
+	if it.e != nil {
+		return false
+	}
+	for it.i+1 >= len(it.page) {
+		if it.last {
+			return false
+		}
+		it.seen[it.q.Cursor] = true
+		p, e := it.s.GetBankAccountTransactionPageContext(it.ctx, it.accountUUID, it.q)
+		if e != nil {
+			it.e = e
+			return false
+		}
+		if p.NextCursor != "" && it.seen[p.NextCursor] {
+			it.e = newError(dutil.NewErr(500, "cursor", []string{fmt.Sprintf("the page of the next cursor '%s' has already been requested", p.NextCursor)}))
+			return false
+		}
+		it.page = p.Transactions
+		it.i = -1
+		it.q.Cursor = p.NextCursor
+		it.last = p.NextCursor == ""
+	}
+	it.i++
+	return true
+}
+
+// Transaction returns the current transaction of the iterator.
+func (it *TransactionIterator) Transaction() Transaction {
+	if it.i < 0 || it.i >= len(it.page) {
+		return Transaction{}
+	}
+	return it.page[it.i]
+}
+
+// Cursor returns the cursor of the page after the current page, which can be
+// used to continue iterating later. It is empty after the last page.
+func (it *TransactionIterator) Cursor() string {
+	return it.q.Cursor
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *TransactionIterator) Err() dutil.Error {
+	return it.e
+}
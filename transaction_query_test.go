@@ -0,0 +1,289 @@
+package bankserv
+
+import (
+	"context"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"net/url"
+	"testing"
+)
+
+func TestTransactionQuery_values(t *testing.T) {
+	active := false
+	minAmount := Money{MinorUnits: -10000}
+	maxAmount := Money{MinorUnits: 50}
+
+	tt := []struct {
+		name string
+		q    TransactionQuery
+		qs   url.Values
+	}{
+		{
+			name: "zero query",
+			q:    TransactionQuery{},
+			qs:   url.Values{},
+		},
+		{
+			name: "date range",
+			q: TransactionQuery{
+				From: timeMustParse("2022-01-01T00:00:00Z"),
+				To:   timeMustParse("2022-01-31T23:59:59Z"),
+			},
+			qs: url.Values{
+				"from": {"2022-01-01T00:00:00Z"},
+				"to":   {"2022-01-31T23:59:59Z"},
+			},
+		},
+		{
+			name: "every filter",
+			q: TransactionQuery{
+				MinAmount: &minAmount,
+				MaxAmount: &maxAmount,
+				Search:    "SUPERSPAR",
+				TagUUIDs: []uuid.UUID{
+					uuid.MustParse("5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90"),
+					uuid.MustParse("8a1f5c2e-3d4b-4e6f-9a7b-0c1d2e3f4a5b"),
+				},
+				Active:   &active,
+				Sort:     SortDateDesc,
+				PageSize: 50,
+				Cursor:   "abc",
+			},
+			qs: url.Values{
+				"min_amount": {"-100.00"},
+				"max_amount": {"0.50"},
+				"search":     {"SUPERSPAR"},
+				"tag_uuid":   {"5f0c1a6e-2b7d-4d8e-9a3f-6c1b2e4d7a90", "8a1f5c2e-3d4b-4e6f-9a7b-0c1d2e3f4a5b"},
+				"active":     {"false"},
+				"sort":       {"-date"},
+				"page_size":  {"50"},
+				"cursor":     {"abc"},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			qs := tc.q.values()
+			if qs.Encode() != tc.qs.Encode() {
+				t.Errorf("expected query string %s got %s", tc.qs.Encode(), qs.Encode())
+			}
+		})
+	}
+}
+
+// transactionPageJSON returns the response body of a page with a single
+// transaction with the UUID.
+func transactionPageJSON(UUID string, nextCursor string) string {
+	return `{"message":"transactions found","data":{"transactions":[{"uuid":"` + UUID + `","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","date":"2022-06-18T15:26:22Z","description":"SUPERSPAR","items":[],"active":true}],"next_cursor":"` + nextCursor + `"},"errors":{}}`
+}
+
+func TestService_GetBankAccountTransactionPage(t *testing.T) {
+	tt := []struct {
+		name     string
+		exchange *microtest.Exchange
+		page     TransactionPage
+		e        dutil.Error
+	}{
+		{
+			name: "invalid cursor",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 400,
+					Body:   `{"message":"BadRequest: Unable to process request","data":{},"errors":{"cursor":["invalid cursor"]}}`,
+				},
+			},
+			page: TransactionPage{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"cursor": {"invalid cursor"},
+				},
+			},
+		},
+		{
+			name: "no transactions",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"transactions found","data":{"transactions":[],"next_cursor":""},"errors":{}}`,
+				},
+			},
+			page: TransactionPage{Transactions: Transactions{}},
+			e:    nil,
+		},
+		{
+			name: "page with next cursor",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   transactionPageJSON("e4bd194d-41e7-4f27-a4a8-161685a9b8b8", "next"),
+				},
+			},
+			page: TransactionPage{
+				Transactions: Transactions{
+					{
+						UUID:        uuid.MustParse("e4bd194d-41e7-4f27-a4a8-161685a9b8b8"),
+						AccountUUID: uuid.MustParse("032203af-6002-4abc-9982-73c577add8df"),
+						Date:        timeMustParse("2022-06-18T15:26:22Z"),
+						Description: "SUPERSPAR",
+						Items:       Items{},
+						Active:      true,
+					},
+				},
+				NextCursor: "next",
+			},
+			e: nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	UUID := uuid.MustParse("032203af-6002-4abc-9982-73c577add8df")
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			p, e := s.GetBankAccountTransactionPage(UUID, TransactionQuery{Search: "SUPERSPAR", PageSize: 1})
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !EqualTransactions(tc.page.Transactions, p.Transactions) {
+				t.Errorf("expected transactions %v got %v", tc.page.Transactions, p.Transactions)
+			}
+			if p.NextCursor != tc.page.NextCursor {
+				t.Errorf("expected next cursor %s got %s", tc.page.NextCursor, p.NextCursor)
+			}
+			q := tc.exchange.Request.URL.Query()
+			if q.Get("uuid") != UUID.String() || q.Get("search") != "SUPERSPAR" || q.Get("page_size") != "1" {
+				t.Errorf("expected query to have uuid, search and page_size got %v", q)
+			}
+		})
+	}
+}
+
+func TestTransactionIterator(t *testing.T) {
+	UUID := uuid.MustParse("032203af-6002-4abc-9982-73c577add8df")
+
+	t.Run("walk through pages", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		exchanges := []*microtest.Exchange{
+			{Response: microtest.Response{Status: 200, Body: transactionPageJSON("e4bd194d-41e7-4f27-a4a8-161685a9b8b8", "page-2")}},
+			{Response: microtest.Response{Status: 200, Body: `{"message":"","data":{"transactions":[],"next_cursor":"page-3"},"errors":{}}`}},
+			{Response: microtest.Response{Status: 200, Body: transactionPageJSON("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82", "")}},
+		}
+		for _, x := range exchanges {
+			ms.Append(x)
+		}
+
+		it := s.IterateBankAccountTransactions(context.Background(), UUID, TransactionQuery{PageSize: 1})
+		if len(ms.Exchanges) != 3 || exchanges[0].Request != nil {
+			t.Errorf("expected no exchange before Next")
+		}
+		var xUUID []string
+		for it.Next() {
+			xUUID = append(xUUID, it.Transaction().UUID.String())
+		}
+		if it.Err() != nil {
+			t.Errorf("unexpected error %v", it.Err())
+		}
+		if len(xUUID) != 2 || xUUID[0] != "e4bd194d-41e7-4f27-a4a8-161685a9b8b8" || xUUID[1] != "d25ac3b1-0a8f-43a3-8da1-d2f22a814a82" {
+			t.Errorf("expected two transactions got %v", xUUID)
+		}
+		cursors := []string{"", "page-2", "page-3"}
+		for i, x := range exchanges {
+			c := x.Request.URL.Query().Get("cursor")
+			if c != cursors[i] {
+				t.Errorf("expected exchange %d to have cursor '%s' got '%s'", i, cursors[i], c)
+			}
+		}
+		if it.Next() {
+			t.Errorf("expected iterator to be done")
+		}
+	})
+
+	t.Run("error on page", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 200, Body: transactionPageJSON("e4bd194d-41e7-4f27-a4a8-161685a9b8b8", "page-2")}})
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 403, Body: `{"message":"","data":{},"errors":{"permission":["Please ensure you have permission"]}}`}})
+
+		it := s.IterateBankAccountTransactions(context.Background(), UUID, TransactionQuery{})
+		n := 0
+		for it.Next() {
+			n++
+		}
+		if n != 1 {
+			t.Errorf("expected 1 transaction got %d", n)
+		}
+		ee := dutil.NewErr(403, "permission", []string{"Please ensure you have permission"})
+		if !dutil.ErrorEqual(ee, it.Err()) {
+			t.Errorf("expected error %v got %v", ee, it.Err())
+		}
+		if it.Cursor() != "page-2" {
+			t.Errorf("expected cursor to resume from %s got %s", "page-2", it.Cursor())
+		}
+	})
+
+	t.Run("repeated cursor", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 200, Body: transactionPageJSON("e4bd194d-41e7-4f27-a4a8-161685a9b8b8", "page-2")}})
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 200, Body: `{"message":"","data":{"transactions":[],"next_cursor":"page-2"},"errors":{}}`}})
+		// the iterator must stop before this exchange
+		last := &microtest.Exchange{Response: microtest.Response{Status: 200, Body: transactionPageJSON("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82", "")}}
+		ms.Append(last)
+
+		it := s.IterateBankAccountTransactions(context.Background(), UUID, TransactionQuery{})
+		n := 0
+		for it.Next() {
+			n++
+		}
+		if n != 1 {
+			t.Errorf("expected 1 transaction got %d", n)
+		}
+		ee := dutil.NewErr(500, "cursor", []string{"the page of the next cursor 'page-2' has already been requested"})
+		if !dutil.ErrorEqual(ee, it.Err()) {
+			t.Errorf("expected error %v got %v", ee, it.Err())
+		}
+		if last.Request != nil {
+			t.Errorf("expected no exchange after the repeated cursor")
+		}
+		if it.Next() {
+			t.Errorf("expected iterator to be done")
+		}
+	})
+
+	t.Run("cursor cycle", func(t *testing.T) {
+		s := NewService("")
+		ms := microtest.MockServer(s.serv)
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 200, Body: transactionPageJSON("e4bd194d-41e7-4f27-a4a8-161685a9b8b8", "page-3")}})
+		ms.Append(&microtest.Exchange{Response: microtest.Response{Status: 200, Body: transactionPageJSON("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82", "page-2")}})
+		// the iterator must stop before this exchange
+		last := &microtest.Exchange{Response: microtest.Response{Status: 200, Body: transactionPageJSON("9b0b8d2c-4c4e-4f0e-9a43-3f1e2f8c6a51", "")}}
+		ms.Append(last)
+
+		it := s.IterateBankAccountTransactions(context.Background(), UUID, TransactionQuery{Cursor: "page-2"})
+		n := 0
+		for it.Next() {
+			n++
+		}
+		// the transactions of the page with the repeated cursor are not returned
+		if n != 1 {
+			t.Errorf("expected 1 transaction got %d", n)
+		}
+		ee := dutil.NewErr(500, "cursor", []string{"the page of the next cursor 'page-2' has already been requested"})
+		if !dutil.ErrorEqual(ee, it.Err()) {
+			t.Errorf("expected error %v got %v", ee, it.Err())
+		}
+		if last.Request != nil {
+			t.Errorf("expected no exchange after the cursor cycle")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package bankserv
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/dottics/dutil"
 	"github.com/google/uuid"
@@ -240,14 +241,8 @@ func TestService_UpdateTransaction(t *testing.T) {
 			},
 		},
 		{
-			name:        "bad request",
-			transaction: Transaction{},
-			exchange: &microtest.Exchange{
-				Response: microtest.Response{
-					Status: 400,
-					Body:   `{"message":"BadRequest: Unable to process request","data":{},"errors":{"uuid":["required field"]}}`,
-				},
-			},
+			name:         "bad request",
+			transaction:  Transaction{},
 			ETransaction: Transaction{},
 			e: &dutil.Err{
 				Status: 400,
@@ -289,7 +284,9 @@ func TestService_UpdateTransaction(t *testing.T) {
 	for i, tc := range tt {
 		name := fmt.Sprintf("%d %s", i, tc.name)
 		t.Run(name, func(t *testing.T) {
-			ms.Append(tc.exchange)
+			if tc.exchange != nil {
+				ms.Append(tc.exchange)
+			}
 
 			tx, e := s.UpdateTransaction(tc.transaction)
 			if !dutil.ErrorEqual(tc.e, e) {
@@ -369,3 +366,156 @@ func TestService_DeleteTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestService_GetTransaction(t *testing.T) {
+	tt := []struct {
+		name         string
+		exchange     *microtest.Exchange
+		ETransaction Transaction
+		status       int
+		e            dutil.Error
+	}{
+		{
+			name: "transaction not found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 404,
+					Body:   `{"message":"NotFound: Unable to find resource","data":{},"errors":{"transaction":["not found"]}}`,
+				},
+			},
+			ETransaction: Transaction{},
+			status:       404,
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"transaction": {"not found"},
+				},
+			},
+		},
+		{
+			name: "no transaction in response",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"transaction found","data":{"transaction":null},"errors":{}}`,
+				},
+			},
+			ETransaction: Transaction{},
+			status:       404,
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"transaction": {"not found"},
+				},
+			},
+		},
+		{
+			name: "transaction found",
+			exchange: &microtest.Exchange{
+				Response: microtest.Response{
+					Status: 200,
+					Body:   `{"message":"transaction found","data":{"transaction":{"uuid":"7f408ea2-f5e5-4547-8f74-c33fe75c3081","bank_account_uuid":"6dedbdf5-84ad-435e-8a2f-26d929e18116","date":"2022-06-19T13:27:19Z","description":"SUPERSPAR","items":[{"uuid":"a03d4ac5-1d5b-465c-9e0a-c7658912c47d","transaction_uuid":"7f408ea2-f5e5-4547-8f74-c33fe75c3081","description":"milk","sku":1,"amount":24.99,"discount":0,"tags":[],"active":true}],"active":true,"create_date":"2022-06-18T15:49:58Z","update_date":"2022-06-18T15:50:06Z"}},"errors":{}}`,
+				},
+			},
+			ETransaction: Transaction{
+				UUID:        uuid.MustParse("7f408ea2-f5e5-4547-8f74-c33fe75c3081"),
+				AccountUUID: uuid.MustParse("6dedbdf5-84ad-435e-8a2f-26d929e18116"),
+				Description: "SUPERSPAR",
+				Date:        timeMustParse("2022-06-19T13:27:19.000Z"),
+				Active:      true,
+				CreateDate:  timeMustParse("2022-06-18T15:49:58.000Z"),
+				UpdateDate:  timeMustParse("2022-06-18T15:50:06.000Z"),
+				Items: Items{
+					{
+						UUID:            uuid.MustParse("a03d4ac5-1d5b-465c-9e0a-c7658912c47d"),
+						TransactionUUID: uuid.MustParse("7f408ea2-f5e5-4547-8f74-c33fe75c3081"),
+						Description:     "milk",
+						SKU:             Units(1),
+						Amount:          Money{MinorUnits: 2499},
+						Tags:            Tags{},
+						Active:          true,
+					},
+				},
+			},
+			e: nil,
+		},
+	}
+
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	UUID := uuid.MustParse("7f408ea2-f5e5-4547-8f74-c33fe75c3081")
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			ms.Append(tc.exchange)
+
+			txn, e := s.GetTransaction(UUID)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && dutil.Inst(e).Status != tc.status {
+				t.Errorf("expected status %d got %d", tc.status, dutil.Inst(e).Status)
+			}
+			if !EqualTransaction(tc.ETransaction, txn) {
+				t.Errorf("expected transaction %v got %v", tc.ETransaction, txn)
+			}
+		})
+	}
+}
+
+func TestTransaction_MarshalJSON(t *testing.T) {
+	tt := []struct {
+		name        string
+		transaction Transaction
+		keys        []string
+		omitted     []string
+	}{
+		{
+			name:        "not set",
+			transaction: Transaction{Description: "SALARY", Date: timeMustParse("2022-06-25T00:00:00Z")},
+			keys:        []string{"uuid", "bank_account_uuid", "date", "description", "items", "active"},
+			omitted:     []string{"counterpart_uuid", "external_id", "value_date"},
+		},
+		{
+			name: "set",
+			transaction: Transaction{
+				CounterpartUUID: uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82"),
+				ExternalID:      "202206250001",
+				Date:            timeMustParse("2022-06-25T00:00:00Z"),
+				ValueDate:       timeMustParsePtr("2022-06-24T00:00:00Z"),
+				Description:     "SALARY",
+			},
+			keys: []string{"counterpart_uuid", "external_id", "value_date"},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			xb, err := json.Marshal(tc.transaction)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			m := map[string]json.RawMessage{}
+			if err := json.Unmarshal(xb, &m); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			for _, k := range tc.keys {
+				if _, ok := m[k]; !ok {
+					t.Errorf("expected key %s in %s", k, xb)
+				}
+			}
+			for _, k := range tc.omitted {
+				if _, ok := m[k]; ok {
+					t.Errorf("expected no key %s in %s", k, xb)
+				}
+			}
+			txn := Transaction{}
+			if err := json.Unmarshal(xb, &txn); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !EqualTransaction(tc.transaction, txn) {
+				t.Errorf("expected transaction %v got %v", tc.transaction, txn)
+			}
+		})
+	}
+}
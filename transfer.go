@@ -0,0 +1,253 @@
+package bankserv
+
+import (
+	"context"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+// Transfer is a transfer of money between two bank accounts, for example
+// from a user's cheque account to the user's savings account. A transfer is
+// a debit transaction of the From bank account and a credit transaction of
+// the To bank account, which are linked by their CounterpartUUID.
+type Transfer struct {
+	From        uuid.UUID
+	To          uuid.UUID
+	Date        time.Time
+	Description string
+	// Amount is the positive amount which is transferred
+	Amount Money
+	// Debit and Credit are the transactions of the transfer, which are set
+	// once the transfer is created
+	Debit  Transaction
+	Credit Transaction
+}
+
+// Validate validates the transfer before the transactions of the transfer
+// are created. A transfer is between two different bank accounts, has a date
+// and a positive amount.
+func (tr Transfer) Validate() dutil.Error {
+	v := validation{}
+	v.require("from", tr.From)
+	v.require("to", tr.To)
+	if tr.From != uuid.Nil && tr.From == tr.To {
+		v.add("to", "must be a different bank account than from")
+	}
+	if tr.Date.IsZero() {
+		v.add("date", "required field")
+	}
+	if tr.Amount.Sign() <= 0 {
+		v.add("amount", "must be positive")
+	}
+	return v.err()
+}
+
+// transaction returns the transaction of the transfer of the bank account
+// with the signed amount.
+func (tr Transfer) transaction(accountUUID uuid.UUID, amount Money) Transaction {
+	return Transaction{
+		AccountUUID: accountUUID,
+		Date:        tr.Date,
+		Description: tr.Description,
+		Items: Items{
+			{
+				Description: tr.Description,
+				SKU:         Units(1),
+				Amount:      amount,
+				Active:      true,
+			},
+		},
+		Active: true,
+	}
+}
+
+// CreateTransfer creates the debit and credit transactions of a transfer
+// between two bank accounts and links the transactions to each other. The
+// transfer is returned with its transactions.
+func (s *Service) CreateTransfer(tr Transfer) (Transfer, dutil.Error) {
+	return s.CreateTransferContext(context.Background(), tr)
+}
+
+// CreateTransferContext is the same as CreateTransfer, the context passed to
+// the function is used to cancel the exchanges with the bank-service.
+//
+// A transfer is not atomic, it is three separate exchanges with the
+// bank-service: the debit transaction is created first, then the credit
+// transaction with the debit as its counterpart, and last the debit is
+// updated with the credit as its counterpart. If any of the exchanges fails,
+// the transactions which were created are deleted again and the error of the
+// failed exchange is returned. If a transaction could not be deleted the
+// error has the key "compensation" with the UUID of the transaction. Until
+// the transfer completes or is compensated, other clients are able to see a
+// debit without a credit.
+//
+// If the context carries an idempotency key, see WithIdempotencyKey, the
+// debit and credit are created with keys derived from the key. The keys of
+// the transactions which are deleted by the compensation are removed from the
+// record of the Service, such that a retry of the transfer with the same key
+// creates the transactions again.
+func (s *Service) CreateTransferContext(ctx context.Context, tr Transfer) (Transfer, dutil.Error) {
+	if e := tr.Validate(); e != nil {
+		return Transfer{}, e
+	}
+	debitCtx, creditCtx := ctx, ctx
+	var debitKey, creditKey string
+	if key, ok := idempotencyKey(ctx); ok {
+		debitKey, creditKey = key+"-debit", key+"-credit"
+		debitCtx = WithIdempotencyKey(ctx, debitKey)
+		creditCtx = WithIdempotencyKey(ctx, creditKey)
+	}
+
+	debit, e := s.CreateTransactionContext(debitCtx, tr.transaction(tr.From, tr.Amount.Neg()))
+	if e != nil {
+		return Transfer{}, e
+	}
+
+	c := tr.transaction(tr.To, tr.Amount)
+	c.CounterpartUUID = debit.UUID
+	credit, e := s.CreateTransactionContext(creditCtx, c)
+	if e != nil {
+		return Transfer{}, s.compensate(e, created{debit.UUID, debitKey})
+	}
+
+	debit.CounterpartUUID = credit.UUID
+	linked, e := s.UpdateTransactionContext(ctx, debit)
+	if e != nil {
+		return Transfer{}, s.compensate(e, created{credit.UUID, creditKey}, created{debit.UUID, debitKey})
+	}
+
+	tr.Debit = linked
+	tr.Credit = credit
+	return tr, nil
+}
+
+// created is a transaction which was created by a transfer and the
+// idempotency key it was created with, which is empty if the caller did not
+// supply a key.
+type created struct {
+	UUID uuid.UUID
+	key  string
+}
+
+// compensate deletes the transactions of a transfer which failed with the
+// error e, and returns e. The idempotency keys of the transactions which are
+// deleted are removed from the record, otherwise a retry with the key returns
+// a transaction which no longer exists. The UUIDs of the transactions which
+// could not be deleted are added to e with the key "compensation".
+func (s *Service) compensate(e dutil.Error, xc ...created) dutil.Error {
+	// the transactions are deleted even if the context of the transfer is done
+	ctx := context.Background()
+	var failed []string
+	for _, c := range xc {
+		if de := s.DeleteTransactionContext(ctx, c.UUID); de != nil {
+			failed = append(failed, c.UUID.String())
+			continue
+		}
+		if c.key != "" {
+			s.idempotency.forget("transaction", c.key)
+		}
+	}
+	if len(failed) == 0 {
+		return e
+	}
+	err := dutil.Inst(e)
+	errs := make(dutil.Errors, len(err.Errors)+1)
+	for k, v := range err.Errors {
+		errs[k] = v
+	}
+	errs["compensation"] = failed
+	return newError(&dutil.Err{
+		Status: err.Status,
+		Errors: errs,
+	})
+}
+
+// IsTransfer reports whether the transaction is one side of a transfer
+// between two bank accounts.
+func (t Transaction) IsTransfer() bool {
+	return t.CounterpartUUID != uuid.Nil
+}
+
+// ExcludeTransfers returns the transactions which are not transfers between
+// two bank accounts, such that reports do not count a transfer as both
+// spending and income. The transactions keep their order.
+func ExcludeTransfers(xt Transactions) Transactions {
+	x := make(Transactions, 0, len(xt))
+	for _, t := range xt {
+		if !t.IsTransfer() {
+			x = append(x, t)
+		}
+	}
+	return x
+}
+
+// TransferMatch is a debit and a credit transaction which are likely the two
+// sides of a transfer which is not linked.
+type TransferMatch struct {
+	Debit  Transaction
+	Credit Transaction
+}
+
+// MatchTransfers finds the pairs of transactions which are likely transfers
+// between two bank accounts but are not linked. A debit and a credit are a
+// pair if they are of different bank accounts, their net amounts cancel out
+// and their dates are at most window apart. Every transaction is part of at
+// most one pair, a debit is paired with the credit closest in date.
+//
+// The pairs are sorted by the date of the debit. Use the UUIDs of a pair to
+// link the transactions with UpdateTransaction.
+func MatchTransfers(xt Transactions, window time.Duration) []TransferMatch {
+	type candidate struct {
+		t   Transaction
+		net Money
+	}
+	var debits, credits []candidate
+	for _, t := range xt {
+		if t.IsTransfer() {
+			continue
+		}
+		net, e := t.Net()
+		if e != nil {
+			continue
+		}
+		switch net.Sign() {
+		case -1:
+			debits = append(debits, candidate{t, net})
+		case 1:
+			credits = append(credits, candidate{t, net})
+		}
+	}
+	sort.SliceStable(debits, func(i, j int) bool {
+		return debits[i].t.Date.Before(debits[j].t.Date)
+	})
+
+	used := make([]bool, len(credits))
+	var xm []TransferMatch
+	for _, d := range debits {
+		best := -1
+		var bestGap time.Duration
+		for j, c := range credits {
+			if used[j] || c.t.AccountUUID == d.t.AccountUUID || !c.net.Equal(d.net.Neg()) {
+				continue
+			}
+			gap := c.t.Date.Sub(d.t.Date)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap > window {
+				continue
+			}
+			if best == -1 || gap < bestGap {
+				best, bestGap = j, gap
+			}
+		}
+		if best == -1 {
+			continue
+		}
+		used[best] = true
+		xm = append(xm, TransferMatch{Debit: d.t, Credit: credits[best].t})
+	}
+	return xm
+}
@@ -0,0 +1,386 @@
+package bankserv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var (
+	chequeUUID  = uuid.MustParse("032203af-6002-4abc-9982-73c577add8df")
+	savingsUUID = uuid.MustParse("7a4c51ab-c87b-4fd3-8d84-b5d2e3e7b0b1")
+	debitUUID   = uuid.MustParse("e4bd194d-41e7-4f27-a4a8-161685a9b8b8")
+	creditUUID  = uuid.MustParse("d25ac3b1-0a8f-43a3-8da1-d2f22a814a82")
+)
+
+var savingsTransfer = Transfer{
+	From:        chequeUUID,
+	To:          savingsUUID,
+	Date:        timeMustParse("2022-06-25T08:00:00Z"),
+	Description: "SAVINGS",
+	Amount:      NewMoney(100000, "ZAR"),
+}
+
+const debitJSON = `{"message":"transaction created","data":{"transaction":{"uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","counterpart_uuid":null,"date":"2022-06-25T08:00:00Z","description":"SAVINGS","items":[{"uuid":"8f8b0d7c-58a7-4a5b-9d2d-55e4f0a1c9f2","transaction_uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","description":"SAVINGS","sku":1,"amount":-1000.00,"active":true}],"active":true}},"errors":{}}`
+const creditJSON = `{"message":"transaction created","data":{"transaction":{"uuid":"d25ac3b1-0a8f-43a3-8da1-d2f22a814a82","bank_account_uuid":"7a4c51ab-c87b-4fd3-8d84-b5d2e3e7b0b1","counterpart_uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","date":"2022-06-25T08:00:00Z","description":"SAVINGS","items":[{"uuid":"1c0e6c43-3f5e-4b57-8a2a-6f1b7c1d0e55","transaction_uuid":"d25ac3b1-0a8f-43a3-8da1-d2f22a814a82","description":"SAVINGS","sku":1,"amount":1000.00,"active":true}],"active":true}},"errors":{}}`
+const linkedDebitJSON = `{"message":"transaction updated","data":{"transaction":{"uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","bank_account_uuid":"032203af-6002-4abc-9982-73c577add8df","counterpart_uuid":"d25ac3b1-0a8f-43a3-8da1-d2f22a814a82","date":"2022-06-25T08:00:00Z","description":"SAVINGS","items":[{"uuid":"8f8b0d7c-58a7-4a5b-9d2d-55e4f0a1c9f2","transaction_uuid":"e4bd194d-41e7-4f27-a4a8-161685a9b8b8","description":"SAVINGS","sku":1,"amount":-1000.00,"active":true}],"active":true}},"errors":{}}`
+const deletedJSON = `{"message":"transaction deleted","data":{},"errors":{}}`
+const serverErrorJSON = `{"message":"internal server error","data":{},"errors":{"internal_server_error":["unable to connect to the database"]}}`
+
+func TestTransfer_Validate(t *testing.T) {
+	same := savingsTransfer
+	same.To = same.From
+	negative := savingsTransfer
+	negative.Amount = NewMoney(-100000, "ZAR")
+
+	tt := []struct {
+		name     string
+		transfer Transfer
+		e        dutil.Error
+	}{
+		{
+			name:     "valid transfer",
+			transfer: savingsTransfer,
+			e:        nil,
+		},
+		{
+			name:     "zero transfer",
+			transfer: Transfer{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"from":   {"required field"},
+					"to":     {"required field"},
+					"date":   {"required field"},
+					"amount": {"must be positive"},
+				},
+			},
+		},
+		{
+			name:     "same bank account",
+			transfer: same,
+			e:        dutil.NewErr(400, "to", []string{"must be a different bank account than from"}),
+		},
+		{
+			name:     "negative amount",
+			transfer: negative,
+			e:        dutil.NewErr(400, "amount", []string{"must be positive"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := tc.transfer.Validate()
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+		})
+	}
+}
+
+func TestService_CreateTransfer(t *testing.T) {
+	ok := func(status int, body string) *microtest.Exchange {
+		return &microtest.Exchange{Response: microtest.Response{Status: status, Body: body}}
+	}
+
+	tt := []struct {
+		name      string
+		exchanges []*microtest.Exchange
+		// deleted are the UUIDs of the transactions which are expected to
+		// be deleted after the exchange at the index failed
+		deleted []uuid.UUID
+		e       dutil.Error
+	}{
+		{
+			name: "successful transfer",
+			exchanges: []*microtest.Exchange{
+				ok(201, debitJSON),
+				ok(201, creditJSON),
+				ok(200, linkedDebitJSON),
+			},
+			e: nil,
+		},
+		{
+			name: "debit fails",
+			exchanges: []*microtest.Exchange{
+				ok(500, serverErrorJSON),
+			},
+			e: dutil.NewErr(500, "internal_server_error", []string{"unable to connect to the database"}),
+		},
+		{
+			name: "credit fails",
+			exchanges: []*microtest.Exchange{
+				ok(201, debitJSON),
+				ok(500, serverErrorJSON),
+				ok(200, deletedJSON),
+			},
+			deleted: []uuid.UUID{debitUUID},
+			e:       dutil.NewErr(500, "internal_server_error", []string{"unable to connect to the database"}),
+		},
+		{
+			name: "link fails",
+			exchanges: []*microtest.Exchange{
+				ok(201, debitJSON),
+				ok(201, creditJSON),
+				ok(500, serverErrorJSON),
+				ok(200, deletedJSON),
+				ok(200, deletedJSON),
+			},
+			deleted: []uuid.UUID{creditUUID, debitUUID},
+			e:       dutil.NewErr(500, "internal_server_error", []string{"unable to connect to the database"}),
+		},
+		{
+			name: "compensation fails",
+			exchanges: []*microtest.Exchange{
+				ok(201, debitJSON),
+				ok(500, serverErrorJSON),
+				ok(500, serverErrorJSON),
+			},
+			deleted: []uuid.UUID{debitUUID},
+			e: &dutil.Err{
+				Status: 500,
+				Errors: map[string][]string{
+					"internal_server_error": {"unable to connect to the database"},
+					"compensation":          {debitUUID.String()},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := NewService("")
+			ms := microtest.MockServer(s.serv)
+			for _, x := range tc.exchanges {
+				ms.Append(x)
+			}
+			// keep the payloads, the request bodies are closed after the
+			// exchange
+			var payloads [][]byte
+			h := ms.Server.Config.Handler
+			ms.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				xb, _ := ioutil.ReadAll(r.Body)
+				payloads = append(payloads, xb)
+				r.Body = ioutil.NopCloser(bytes.NewReader(xb))
+				h.ServeHTTP(w, r)
+			})
+
+			tr, e := s.CreateTransfer(savingsTransfer)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			for _, x := range tc.exchanges {
+				if x.Request == nil {
+					t.Errorf("expected every exchange to be sent")
+				}
+			}
+			if e != nil {
+				n := len(tc.exchanges) - len(tc.deleted)
+				for j, UUID := range tc.deleted {
+					r := tc.exchanges[n+j].Request
+					if r == nil || r.Method != "DELETE" || r.URL.Query().Get("uuid") != UUID.String() {
+						t.Errorf("expected transaction %s to be deleted", UUID)
+					}
+				}
+				return
+			}
+
+			if tr.Debit.UUID != debitUUID || tr.Debit.CounterpartUUID != creditUUID {
+				t.Errorf("expected debit linked to credit got %v", tr.Debit)
+			}
+			if tr.Credit.UUID != creditUUID || tr.Credit.CounterpartUUID != debitUUID {
+				t.Errorf("expected credit linked to debit got %v", tr.Credit)
+			}
+
+			// the payloads of the debit and credit
+			xt := make([]Transaction, 2)
+			for j := range xt {
+				if err := json.Unmarshal(payloads[j], &xt[j]); err != nil {
+					t.Fatalf("unable to decode payload: %v", err)
+				}
+			}
+			if xt[0].AccountUUID != chequeUUID || xt[0].Items[0].Amount.Decimal() != "-1000.00" {
+				t.Errorf("expected a debit of -1000.00 from %s got %v", chequeUUID, xt[0])
+			}
+			if xt[1].AccountUUID != savingsUUID || xt[1].Items[0].Amount.Decimal() != "1000.00" || xt[1].CounterpartUUID != debitUUID {
+				t.Errorf("expected a credit of 1000.00 to %s got %v", savingsUUID, xt[1])
+			}
+		})
+	}
+}
+
+func TestService_CreateTransfer_idempotency(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	xx := []*microtest.Exchange{
+		{Response: microtest.Response{Status: 201, Body: debitJSON}},
+		{Response: microtest.Response{Status: 201, Body: creditJSON}},
+		{Response: microtest.Response{Status: 200, Body: linkedDebitJSON}},
+	}
+	for _, x := range xx {
+		ms.Append(x)
+	}
+
+	ctx := WithIdempotencyKey(context.Background(), "transfer-1")
+	if _, e := s.CreateTransferContext(ctx, savingsTransfer); e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	for i, key := range []string{"transfer-1-debit", "transfer-1-credit"} {
+		if got := xx[i].Request.Header.Get("Idempotency-Key"); got != key {
+			t.Errorf("expected idempotency key %s got %s", key, got)
+		}
+	}
+}
+
+func TestService_CreateTransfer_retryAfterCompensation(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	xx := []*microtest.Exchange{
+		// the first attempt fails to create the credit and deletes the debit
+		{Response: microtest.Response{Status: 201, Body: debitJSON}},
+		{Response: microtest.Response{Status: 500, Body: serverErrorJSON}},
+		{Response: microtest.Response{Status: 200, Body: deletedJSON}},
+		// the retry creates the debit again
+		{Response: microtest.Response{Status: 201, Body: debitJSON}},
+		{Response: microtest.Response{Status: 201, Body: creditJSON}},
+		{Response: microtest.Response{Status: 200, Body: linkedDebitJSON}},
+	}
+	for _, x := range xx {
+		ms.Append(x)
+	}
+
+	ctx := WithIdempotencyKey(context.Background(), "transfer-1")
+	if _, e := s.CreateTransferContext(ctx, savingsTransfer); e == nil {
+		t.Fatalf("expected the first attempt to fail")
+	}
+	tr, e := s.CreateTransferContext(ctx, savingsTransfer)
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	for i, x := range xx {
+		if x.Request == nil {
+			t.Fatalf("expected exchange %d to be sent", i)
+		}
+	}
+	r := xx[3].Request
+	if r.Method != "POST" || r.Header.Get("Idempotency-Key") != "transfer-1-debit" {
+		t.Errorf("expected the debit to be created again got %s %s", r.Method, r.URL)
+	}
+	if tr.Debit.CounterpartUUID != creditUUID || tr.Credit.CounterpartUUID != debitUUID {
+		t.Errorf("expected linked transactions got %v", tr)
+	}
+}
+
+func TestService_CreateTransfer_invalid(t *testing.T) {
+	s := NewService("")
+	ms := microtest.MockServer(s.serv)
+	x := &microtest.Exchange{Response: microtest.Response{Status: 500}}
+	ms.Append(x)
+
+	_, e := s.CreateTransfer(Transfer{From: chequeUUID, To: chequeUUID})
+	if !errors.Is(e, ErrValidation) {
+		t.Errorf("expected a validation error got %v", e)
+	}
+	if x.Request != nil {
+		t.Errorf("expected no exchange with the bank-service")
+	}
+}
+
+func TestExcludeTransfers(t *testing.T) {
+	a := txn("2022-06-01T10:00:00Z", -5000)
+	b := txn("2022-06-02T10:00:00Z", -100000)
+	b.CounterpartUUID = creditUUID
+	c := txn("2022-06-03T10:00:00Z", 25000)
+
+	xt := ExcludeTransfers(Transactions{a, b, c})
+	if len(xt) != 2 || !EqualTransaction(xt[0], a) || !EqualTransaction(xt[1], c) {
+		t.Errorf("expected transactions %v got %v", Transactions{a, c}, xt)
+	}
+	if !b.IsTransfer() || a.IsTransfer() {
+		t.Errorf("expected only the linked transaction to be a transfer")
+	}
+}
+
+func TestMatchTransfers(t *testing.T) {
+	at := func(account uuid.UUID, date string, amount int64) Transaction {
+		t := txn(date, amount)
+		t.UUID = uuid.New()
+		t.AccountUUID = account
+		return t
+	}
+	debit := at(chequeUUID, "2022-06-25T08:00:00Z", -100000)
+	credit := at(savingsUUID, "2022-06-26T09:00:00Z", 100000)
+	late := at(savingsUUID, "2022-07-05T09:00:00Z", 100000)
+	closer := at(savingsUUID, "2022-06-25T10:00:00Z", 100000)
+	own := at(chequeUUID, "2022-06-25T09:00:00Z", 100000)
+	other := at(savingsUUID, "2022-06-25T09:00:00Z", 99999)
+	linked := at(savingsUUID, "2022-06-25T08:00:00Z", 100000)
+	linked.CounterpartUUID = debitUUID
+	second := at(chequeUUID, "2022-06-27T08:00:00Z", -100000)
+
+	tt := []struct {
+		name string
+		xt   Transactions
+		xm   []TransferMatch
+	}{
+		{
+			name: "matching debit and credit",
+			xt:   Transactions{credit, debit},
+			xm:   []TransferMatch{{Debit: debit, Credit: credit}},
+		},
+		{
+			name: "outside the window",
+			xt:   Transactions{debit, late},
+			xm:   nil,
+		},
+		{
+			name: "closest credit",
+			xt:   Transactions{debit, credit, closer},
+			xm:   []TransferMatch{{Debit: debit, Credit: closer}},
+		},
+		{
+			name: "same account, different amount and linked",
+			xt:   Transactions{debit, own, other, linked},
+			xm:   nil,
+		},
+		{
+			name: "every transaction at most once",
+			xt:   Transactions{second, debit, credit},
+			xm:   []TransferMatch{{Debit: debit, Credit: credit}},
+		},
+		{
+			name: "two transfers",
+			xt:   Transactions{second, debit, credit, closer},
+			xm: []TransferMatch{
+				{Debit: debit, Credit: closer},
+				{Debit: second, Credit: credit},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			xm := MatchTransfers(tc.xt, 72*time.Hour)
+			if len(xm) != len(tc.xm) {
+				t.Fatalf("expected %d matches got %d: %v", len(tc.xm), len(xm), xm)
+			}
+			for j, m := range xm {
+				if m.Debit.UUID != tc.xm[j].Debit.UUID || m.Credit.UUID != tc.xm[j].Credit.UUID {
+					t.Errorf("expected match %d %v got %v", j, tc.xm[j], m)
+				}
+			}
+		})
+	}
+}
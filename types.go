@@ -9,6 +9,8 @@ type Bank struct {
 	UUID       uuid.UUID `json:"uuid"`
 	Name       string    `json:"name"`
 	BranchCode string    `json:"branch_code"`
+	// BIC is the optional SWIFT code of an international bank
+	BIC        string    `json:"bic,omitempty"`
 	Active     bool      `json:"active"`
 	CreateDate time.Time `json:"create_date"`
 	UpdateDate time.Time `json:"update_date"`
@@ -16,11 +18,13 @@ type Bank struct {
 type Banks []Bank
 
 type Tag struct {
-	UUID       uuid.UUID `json:"uuid"`
-	Tag        string    `json:"tag"`
-	Active     bool      `json:"active"`
-	CreateDate time.Time `json:"create_date"`
-	UpdateDate time.Time `json:"update_date"`
+	UUID             uuid.UUID `json:"uuid"`
+	UserUUID         uuid.UUID `json:"user_uuid"`
+	OrganisationUUID uuid.UUID `json:"organisation_uuid"`
+	Tag              string    `json:"tag"`
+	Active           bool      `json:"active"`
+	CreateDate       time.Time `json:"create_date"`
+	UpdateDate       time.Time `json:"update_date"`
 }
 type Tags []Tag
 
@@ -28,9 +32,9 @@ type Item struct {
 	UUID            uuid.UUID `json:"uuid"`
 	TransactionUUID uuid.UUID `json:"transaction_uuid"`
 	Description     string    `json:"description"`
-	SKU             float32   `json:"sku"`
-	Amount          float32   `json:"amount"`
-	Discount        float32   `json:"discount"`
+	SKU             Quantity  `json:"sku"`
+	Amount          Money     `json:"amount"`
+	Discount        Money     `json:"discount"`
 	Tags            []Tag     `json:"tags"`
 	Active          bool      `json:"active"`
 	CreateDate      time.Time `json:"create_date"`
@@ -38,26 +42,80 @@ type Item struct {
 }
 type Items []Item
 
+// Transaction is a transaction of a bank account. The CounterpartUUID of a
+// transfer between two bank accounts is the UUID of the transaction on the
+// other side of the transfer. The ExternalID is the ID of an imported
+// transaction in the bank statement, such as the FITID of an OFX statement.
+// The Date is the date the transaction is booked and the ValueDate, if not
+// nil, is the date the amount of the transaction takes effect for interest.
+//
+// The CounterpartUUID, ExternalID and ValueDate are left out of the JSON of a
+// transaction if they are not set.
 type Transaction struct {
-	UUID        uuid.UUID `json:"uuid"`
-	AccountUUID uuid.UUID `json:"bank_account_uuid"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
-	Items       []Item    `json:"items"`
-	Active      bool      `json:"active"`
-	CreateDate  time.Time `json:"create_date"`
-	UpdateDate  time.Time `json:"update_date"`
+	UUID            uuid.UUID  `json:"uuid"`
+	AccountUUID     uuid.UUID  `json:"bank_account_uuid"`
+	CounterpartUUID uuid.UUID  `json:"counterpart_uuid"`
+	ExternalID      string     `json:"external_id,omitempty"`
+	Date            time.Time  `json:"date"`
+	ValueDate       *time.Time `json:"value_date,omitempty"`
+	Description     string     `json:"description"`
+	Items           []Item     `json:"items"`
+	Active          bool       `json:"active"`
+	CreateDate      time.Time  `json:"create_date"`
+	UpdateDate      time.Time  `json:"update_date"`
 }
 type Transactions []Transaction
 
+// AccountType is the type of a bank account.
+type AccountType string
+
+const (
+	AccountTypeCheque     AccountType = "cheque"
+	AccountTypeSavings    AccountType = "savings"
+	AccountTypeCreditCard AccountType = "credit_card"
+	AccountTypeLoan       AccountType = "loan"
+	AccountTypeCash       AccountType = "cash"
+)
+
+// accountTypeLabels are the display names of the account types.
+var accountTypeLabels = map[AccountType]string{
+	AccountTypeCheque:     "Cheque",
+	AccountTypeSavings:    "Savings",
+	AccountTypeCreditCard: "Credit Card",
+	AccountTypeLoan:       "Loan",
+	AccountTypeCash:       "Cash",
+}
+
+// String returns the display name of the account type, for example "Credit
+// Card". An unknown account type is returned as is.
+func (t AccountType) String() string {
+	if s, ok := accountTypeLabels[t]; ok {
+		return s
+	}
+	return string(t)
+}
+
+// BankAccount is an account of a user or an organisation at a bank. The Name
+// is the name the owner gave the bank account, for example "Salary", and the
+// OpeningBalance is in the Currency of the bank account. The IBAN of an
+// international account is optional and in the electronic format.
+//
+// The BankUUID, AccountType, Currency, Name and OpeningBalance are left out of
+// the JSON of a bank account if they are not set.
 type BankAccount struct {
-	UUID             uuid.UUID `json:"uuid"`
-	UserUUID         uuid.UUID `json:"user_uuid"`
-	OrganisationUUID uuid.UUID `json:"organisation_uuid"`
-	AccountNumber    string    `json:"account_number"`
-	Active           bool      `json:"active"`
-	CreateDate       time.Time `json:"create_date"`
-	UpdateDate       time.Time `json:"update_date"`
+	UUID             uuid.UUID   `json:"uuid"`
+	UserUUID         uuid.UUID   `json:"user_uuid"`
+	OrganisationUUID uuid.UUID   `json:"organisation_uuid"`
+	BankUUID         uuid.UUID   `json:"bank_uuid"`
+	AccountType      AccountType `json:"account_type,omitempty"`
+	Currency         string      `json:"currency,omitempty"`
+	Name             string      `json:"name,omitempty"`
+	OpeningBalance   Money       `json:"opening_balance"`
+	AccountNumber    string      `json:"account_number"`
+	IBAN             string      `json:"iban,omitempty"`
+	Active           bool        `json:"active"`
+	CreateDate       time.Time   `json:"create_date"`
+	UpdateDate       time.Time   `json:"update_date"`
 }
 type BankAccounts []BankAccount
 
@@ -69,3 +127,10 @@ func timeMustParse(value string) time.Time {
 	t, _ := time.Parse(time.RFC3339, value)
 	return t
 }
+
+// timeMustParsePtr is the same as timeMustParse, but returns a pointer to the
+// time for the optional time fields such as Transaction.ValueDate.
+func timeMustParsePtr(value string) *time.Time {
+	t := timeMustParse(value)
+	return &t
+}
@@ -0,0 +1,207 @@
+package bankserv
+
+import (
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// validation collects the errors of a payload keyed by the JSON name of the
+// field, in the same shape as the errors of the bank-service.
+type validation dutil.Errors
+
+// add adds the error messages of the field.
+func (v validation) add(field string, message ...string) {
+	v[field] = append(v[field], message...)
+}
+
+// require adds a "required field" error if the UUID of the field is the nil
+// UUID.
+func (v validation) require(field string, UUID uuid.UUID) {
+	if UUID == uuid.Nil {
+		v.add(field, "required field")
+	}
+}
+
+// owner adds an error if not exactly one of the user and organisation UUIDs is
+// set, a resource belongs to either a user or an organisation. An update is
+// not required to set the owner.
+func (v validation) owner(userUUID, organisationUUID uuid.UUID, update bool) {
+	if !update && userUUID == uuid.Nil && organisationUUID == uuid.Nil {
+		v.add("user_uuid", "required field if organisation_uuid is not set")
+		v.add("organisation_uuid", "required field if user_uuid is not set")
+	}
+	if userUUID != uuid.Nil && organisationUUID != uuid.Nil {
+		v.add("user_uuid", "only one of user_uuid and organisation_uuid may be set")
+		v.add("organisation_uuid", "only one of user_uuid and organisation_uuid may be set")
+	}
+}
+
+// err returns the ErrValidation error with the status 400 of the collected
+// errors, or nil if there are no errors.
+func (v validation) err() dutil.Error {
+	if len(v) == 0 {
+		return nil
+	}
+	return newError(&dutil.Err{
+		Status: 400,
+		Errors: dutil.Errors(v),
+	})
+}
+
+// validator is a payload which is able to validate its fields. The payload of
+// an update only has the fields which are updated, therefore the fields which
+// are required to create a resource are not required to update a resource.
+type validator interface {
+	validate(v validation, update bool)
+}
+
+// validateUpdate validates the payload of an update, which requires the UUID
+// of the resource to update in addition to the fields of the payload.
+func validateUpdate(UUID uuid.UUID, x validator) dutil.Error {
+	v := validation{}
+	v.require("uuid", UUID)
+	x.validate(v, true)
+	return v.err()
+}
+
+// Validate validates the bank account before it is created by the
+// bank-service. A bank account belongs to either a user or an organisation
+// and has an account number or a valid IBAN. The account type and currency
+// are optional, and the opening balance is in the currency of the bank
+// account. The errors are keyed by the field which is
+// invalid and the error is of the kind ErrValidation.
+//
+// CreateBankAccount validates the bank account before the exchange with the
+// bank-service. UpdateBankAccount only requires the UUID of the bank account
+// and validates the fields which are set.
+func (b BankAccount) Validate() dutil.Error {
+	v := validation{}
+	b.validate(v, false)
+	return v.err()
+}
+
+func (b BankAccount) validate(v validation, update bool) {
+	v.owner(b.UserUUID, b.OrganisationUUID, update)
+	if !update && strings.TrimSpace(b.AccountNumber) == "" && b.IBAN == "" {
+		v.add("account_number", "required field if iban is not set")
+	}
+	if b.IBAN != "" {
+		if _, e := ParseIBAN(b.IBAN); e != nil {
+			v.add("iban", dutil.Inst(e).Errors["iban"]...)
+		}
+	}
+	if _, ok := accountTypeLabels[b.AccountType]; b.AccountType != "" && !ok {
+		v.add("account_type", fmt.Sprintf("unknown account type '%s'", b.AccountType))
+	}
+	if b.Currency != "" && !isCurrencyCode(b.Currency) {
+		v.add("currency", fmt.Sprintf("invalid currency code '%s'", b.Currency))
+	}
+	// a zero opening balance without a currency is in any currency
+	unset := b.OpeningBalance.IsZero() && b.OpeningBalance.Currency == ""
+	currency := NewMoney(0, b.Currency).currency()
+	if !unset && b.OpeningBalance.currency() != currency {
+		v.add("opening_balance", fmt.Sprintf("currency %s does not match the bank account's currency %s", b.OpeningBalance.currency(), currency))
+	}
+}
+
+// isCurrencyCode reports whether the code has the format of an ISO 4217
+// currency code, three letters.
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range strings.ToUpper(code) {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate validates the bank before it is created or updated by the
+// bank-service. The BIC of a bank is optional, if it is set it must be valid.
+func (b Bank) Validate() dutil.Error {
+	v := validation{}
+	if b.BIC != "" {
+		if _, e := ParseBIC(b.BIC); e != nil {
+			v.add("bic", dutil.Inst(e).Errors["bic"]...)
+		}
+	}
+	return v.err()
+}
+
+// Validate validates the transaction and its items before it is created by
+// the bank-service. A transaction has a bank account and a date, and the items of
+// the transaction belong to the transaction. The errors of an item are keyed
+// by the index of the item, for example "items[0].sku".
+func (t Transaction) Validate() dutil.Error {
+	v := validation{}
+	t.validate(v, false)
+	return v.err()
+}
+
+func (t Transaction) validate(v validation, update bool) {
+	if !update {
+		v.require("bank_account_uuid", t.AccountUUID)
+		if t.Date.IsZero() {
+			v.add("date", "required field")
+		}
+	}
+	for j, i := range t.Items {
+		prefix := fmt.Sprintf("items[%d].", j)
+		// the items of a new transaction do not have a transaction yet
+		if i.TransactionUUID != uuid.Nil && i.TransactionUUID != t.UUID {
+			v.add(prefix+"transaction_uuid", "does not match the transaction's uuid")
+		}
+		iv := validation{}
+		i.validateFields(iv)
+		for k, xs := range iv {
+			for _, s := range xs {
+				v.add(prefix+k, s)
+			}
+		}
+	}
+}
+
+// Validate validates the item before it is created by the bank-service. An item
+// belongs to a transaction, has a SKU that is not negative and a discount in
+// the same currency as the amount.
+func (i Item) Validate() dutil.Error {
+	v := validation{}
+	i.validate(v, false)
+	return v.err()
+}
+
+func (i Item) validate(v validation, update bool) {
+	if !update {
+		v.require("transaction_uuid", i.TransactionUUID)
+	}
+	i.validateFields(v)
+}
+
+// validateFields validates the fields of the item without the transaction.
+func (i Item) validateFields(v validation) {
+	if i.SKU.Sign() < 0 {
+		v.add("sku", "must not be negative")
+	}
+	if !i.Discount.IsZero() && i.Discount.currency() != i.Amount.currency() {
+		v.add("discount", fmt.Sprintf("currency %s does not match the amount's currency %s", i.Discount.currency(), i.Amount.currency()))
+	}
+}
+
+// Validate validates the tag before it is created by the bank-service. A tag
+// belongs to either a user or an organisation and has a name.
+func (t Tag) Validate() dutil.Error {
+	v := validation{}
+	t.validate(v, false)
+	return v.err()
+}
+
+func (t Tag) validate(v validation, update bool) {
+	v.owner(t.UserUUID, t.OrganisationUUID, update)
+	if !update && strings.TrimSpace(t.Tag) == "" {
+		v.add("tag", "required field")
+	}
+}
@@ -0,0 +1,385 @@
+package bankserv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dottics/dutil"
+	"github.com/google/uuid"
+	"github.com/johannesscr/micro/microtest"
+	"testing"
+)
+
+func TestBankAccount_Validate(t *testing.T) {
+	both := userBankAccount
+	both.OrganisationUUID = organisationBankAccount.OrganisationUUID
+
+	tt := []struct {
+		name        string
+		bankAccount BankAccount
+		e           dutil.Error
+	}{
+		{
+			name:        "user bank account",
+			bankAccount: userBankAccount,
+			e:           nil,
+		},
+		{
+			name:        "organisation bank account",
+			bankAccount: organisationBankAccount,
+			e:           nil,
+		},
+		{
+			name:        "no owner",
+			bankAccount: BankAccount{AccountNumber: "098765432109"},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"user_uuid":         {"required field if organisation_uuid is not set"},
+					"organisation_uuid": {"required field if user_uuid is not set"},
+				},
+			},
+		},
+		{
+			name:        "user and organisation",
+			bankAccount: both,
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"user_uuid":         {"only one of user_uuid and organisation_uuid may be set"},
+					"organisation_uuid": {"only one of user_uuid and organisation_uuid may be set"},
+				},
+			},
+		},
+		{
+			name:        "no account number",
+			bankAccount: BankAccount{UserUUID: userBankAccount.UserUUID, AccountNumber: " "},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"account_number": {"required field if iban is not set"},
+				},
+			},
+		},
+		{
+			name: "international bank account",
+			bankAccount: BankAccount{
+				OrganisationUUID: organisationBankAccount.OrganisationUUID,
+				IBAN:             "GB82WEST12345698765432",
+			},
+			e: nil,
+		},
+		{
+			name: "account type, currency and opening balance",
+			bankAccount: BankAccount{
+				UserUUID:       userBankAccount.UserUUID,
+				AccountNumber:  "62001238911",
+				AccountType:    AccountTypeSavings,
+				Currency:       "usd",
+				OpeningBalance: NewMoney(150000, "USD"),
+			},
+			e: nil,
+		},
+		{
+			name: "invalid account type, currency and opening balance",
+			bankAccount: BankAccount{
+				UserUUID:       userBankAccount.UserUUID,
+				AccountNumber:  "62001238911",
+				AccountType:    "current",
+				Currency:       "US$",
+				OpeningBalance: NewMoney(150000, "EUR"),
+			},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"account_type":    {"unknown account type 'current'"},
+					"currency":        {"invalid currency code 'US$'"},
+					"opening_balance": {"currency EUR does not match the bank account's currency US$"},
+				},
+			},
+		},
+		{
+			name: "invalid iban",
+			bankAccount: BankAccount{
+				OrganisationUUID: organisationBankAccount.OrganisationUUID,
+				AccountNumber:    "12345698765432",
+				IBAN:             "GB82 WEST 1234 5698 7654 33",
+			},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"iban": {"checksum does not match"},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := tc.bankAccount.Validate()
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if e != nil && (dutil.Inst(e).Status != 400 || !errors.Is(e, ErrValidation)) {
+				t.Errorf("expected a validation error with status 400 got %v", e)
+			}
+		})
+	}
+}
+
+func TestTransaction_Validate(t *testing.T) {
+	UUID := uuid.MustParse("e4bd194d-41e7-4f27-a4a8-161685a9b8b8")
+	withItems := superspar
+	withItems.UUID = UUID
+	withItems.Items = Items{
+		{TransactionUUID: UUID, Description: "milk", SKU: Units(1)},
+		{Description: "bread", SKU: Units(1)},
+	}
+	mismatch := superspar
+	mismatch.UUID = UUID
+	mismatch.Items = Items{
+		{TransactionUUID: UUID, Description: "milk", SKU: Units(1)},
+		{TransactionUUID: milk.TransactionUUID, Description: "bread", SKU: Units(-1)},
+	}
+
+	tt := []struct {
+		name        string
+		transaction Transaction
+		e           dutil.Error
+	}{
+		{
+			name:        "valid transaction",
+			transaction: superspar,
+			e:           nil,
+		},
+		{
+			name:        "valid transaction with items",
+			transaction: withItems,
+			e:           nil,
+		},
+		{
+			name:        "zero transaction",
+			transaction: Transaction{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"bank_account_uuid": {"required field"},
+					"date":              {"required field"},
+				},
+			},
+		},
+		{
+			name:        "invalid items",
+			transaction: mismatch,
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"items[1].transaction_uuid": {"does not match the transaction's uuid"},
+					"items[1].sku":              {"must not be negative"},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := tc.transaction.Validate()
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+		})
+	}
+}
+
+func TestItem_Validate(t *testing.T) {
+	tt := []struct {
+		name string
+		item Item
+		e    dutil.Error
+	}{
+		{
+			name: "valid item",
+			item: milk,
+			e:    nil,
+		},
+		{
+			name: "no transaction",
+			item: Item{Description: "milk"},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"transaction_uuid": {"required field"},
+				},
+			},
+		},
+		{
+			name: "invalid sku and discount",
+			item: Item{
+				TransactionUUID: milk.TransactionUUID,
+				SKU:             Units(-2),
+				Amount:          NewMoney(2499, "ZAR"),
+				Discount:        NewMoney(250, "USD"),
+			},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"sku":      {"must not be negative"},
+					"discount": {"currency USD does not match the amount's currency ZAR"},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := tc.item.Validate()
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+		})
+	}
+}
+
+func TestTag_Validate(t *testing.T) {
+	tt := []struct {
+		name string
+		tag  Tag
+		e    dutil.Error
+	}{
+		{
+			name: "valid tag",
+			tag:  groceries,
+			e:    nil,
+		},
+		{
+			name: "zero tag",
+			tag:  Tag{},
+			e: &dutil.Err{
+				Status: 400,
+				Errors: map[string][]string{
+					"user_uuid":         {"required field if organisation_uuid is not set"},
+					"organisation_uuid": {"required field if user_uuid is not set"},
+					"tag":               {"required field"},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			e := tc.tag.Validate()
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+		})
+	}
+}
+
+func TestService_validate(t *testing.T) {
+	both := userBankAccount
+	both.OrganisationUUID = organisationBankAccount.OrganisationUUID
+	mismatch := superspar
+	mismatch.UUID = uuid.MustParse("e4bd194d-41e7-4f27-a4a8-161685a9b8b8")
+	mismatch.Items = Items{{TransactionUUID: milk.TransactionUUID}}
+
+	tt := []struct {
+		name string
+		f    func(s *Service) dutil.Error
+		e    dutil.Error
+	}{
+		{
+			name: "create bank account with user and organisation",
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateBankAccount(both)
+				return e
+			},
+			e: &dutil.Err{
+				Errors: map[string][]string{
+					"user_uuid":         {"only one of user_uuid and organisation_uuid may be set"},
+					"organisation_uuid": {"only one of user_uuid and organisation_uuid may be set"},
+				},
+			},
+		},
+		{
+			name: "create bank with invalid bic",
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateBank(Bank{Name: "Barclays", BIC: "BARCXXLL"})
+				return e
+			},
+			e: dutil.NewErr(400, "bic", []string{"unknown country 'XX'"}),
+		},
+		{
+			name: "update bank account without uuid",
+			f: func(s *Service) dutil.Error {
+				_, e := s.UpdateBankAccount(BankAccount{AccountNumber: "098765432109"})
+				return e
+			},
+			e: dutil.NewErr(400, "uuid", []string{"required field"}),
+		},
+		{
+			name: "create transaction without date",
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateTransaction(Transaction{AccountUUID: superspar.AccountUUID})
+				return e
+			},
+			e: dutil.NewErr(400, "date", []string{"required field"}),
+		},
+		{
+			name: "update transaction with another transaction's item",
+			f: func(s *Service) dutil.Error {
+				_, e := s.UpdateTransaction(mismatch)
+				return e
+			},
+			e: dutil.NewErr(400, "items[0].transaction_uuid", []string{"does not match the transaction's uuid"}),
+		},
+		{
+			name: "create item without transaction",
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateItem(Item{Description: "milk"})
+				return e
+			},
+			e: dutil.NewErr(400, "transaction_uuid", []string{"required field"}),
+		},
+		{
+			name: "update item without uuid",
+			f: func(s *Service) dutil.Error {
+				_, e := s.UpdateItem(Item{Description: "milk"})
+				return e
+			},
+			e: dutil.NewErr(400, "uuid", []string{"required field"}),
+		},
+		{
+			name: "create tag without name",
+			f: func(s *Service) dutil.Error {
+				_, e := s.CreateTag(Tag{UserUUID: groceries.UserUUID})
+				return e
+			},
+			e: dutil.NewErr(400, "tag", []string{"required field"}),
+		},
+	}
+
+	for i, tc := range tt {
+		name := fmt.Sprintf("%d %s", i, tc.name)
+		t.Run(name, func(t *testing.T) {
+			s := NewService("")
+			ms := microtest.MockServer(s.serv)
+			// the exchange is never sent, the payload is invalid
+			x := &microtest.Exchange{Response: microtest.Response{Status: 500}}
+			ms.Append(x)
+
+			e := tc.f(s)
+			if !dutil.ErrorEqual(tc.e, e) {
+				t.Errorf("expected error %v got %v", tc.e, e)
+			}
+			if !errors.Is(e, ErrValidation) {
+				t.Errorf("expected a validation error got %v", e)
+			}
+			if x.Request != nil {
+				t.Errorf("expected no exchange with the bank-service")
+			}
+		})
+	}
+}